@@ -0,0 +1,236 @@
+package ascanius
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Deprecation describes a deprecated key found in the configuration.
+type Deprecation struct {
+	// Key is the deprecated key path
+	Key string
+	// Replacement is the key path Key binds to, empty when the field
+	// itself is deprecated
+	Replacement string
+	// Source is the highest priority source setting Key, empty when unknown
+	Source  string
+	Message string
+}
+
+func (d Deprecation) String() string {
+	msg := d.Message
+	if msg == "" && d.Replacement != "" {
+		msg = "use " + d.Replacement
+	}
+	s := fmt.Sprintf("%s is deprecated", d.Key)
+	if d.Source != "" {
+		s += fmt.Sprintf(" (set in %s)", d.Source)
+	}
+	if msg != "" {
+		s += ": " + msg
+	}
+	return s
+}
+
+// AliasConflictError reports a key set both under its current name and
+// under a deprecated alias, with different values.
+type AliasConflictError struct {
+	Key   string
+	Alias string
+}
+
+func (e *AliasConflictError) Error() string {
+	return fmt.Sprintf("%s and its deprecated alias %s are both set, to different values", e.Key, e.Alias)
+}
+
+// aliasPlan holds the alias and deprecated tags of a field. key and aliases
+// are relative to the struct the plan was built for.
+type aliasPlan struct {
+	key        string
+	aliases    []string
+	deprecated string
+}
+
+// collectAliases returns the alias plans of typ and of the structs nested in
+// its fields. Structs inside slices and maps are not visited.
+func collectAliases(plan *bindPlan, typ reflect.Type) []aliasPlan {
+	var out []aliasPlan
+	for _, field := range plan.fields {
+		tag := typ.Field(field.index).Tag
+		if alias, deprecated := tag.Get("alias"), tag.Get("deprecated"); alias != "" || deprecated != "" {
+			ap := aliasPlan{key: field.key, deprecated: deprecated}
+			for _, a := range strings.Split(alias, ",") {
+				if a = strings.TrimSpace(a); a != "" {
+					ap.aliases = append(ap.aliases, normalizeKeyPath(a))
+				}
+			}
+			out = append(out, ap)
+		}
+
+		if field.typ.Kind() == reflect.Struct {
+			for _, nested := range planFor(field.typ).aliases {
+				nested.key = joinKey(field.key, nested.key)
+				// the aliases of a field are relative to its own struct
+				aliases := make([]string, len(nested.aliases))
+				for i, a := range nested.aliases {
+					aliases[i] = joinKey(field.key, a)
+				}
+				nested.aliases = aliases
+				out = append(out, nested)
+			}
+		}
+	}
+	return out
+}
+
+// Aliases maps deprecated key paths to the key paths that replace them, e.g.
+// "mongo_host" to "mongo.host". Values under an old key are moved to the new
+// key when sources are merged, reporting a Deprecation.
+func (b *Builder) Aliases(aliases map[string]string) *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for old, key := range aliases {
+		b.aliases[normalizeKeyPath(old)] = normalizeKeyPath(key)
+	}
+	b.merged = nil
+	return b
+}
+
+// OnDeprecated sets the function called for every deprecated key found while
+// loading. By default deprecations are logged with slog.Warn. fn is called
+// once the builder is unlocked, so it may call its methods.
+func (b *Builder) OnDeprecated(fn func(Deprecation)) *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onDeprecated = fn
+	return b
+}
+
+// deprecated records d, to be reported by unlock. The caller must hold b.mu.
+func (b *Builder) deprecated(d Deprecation) {
+	d.Source = b.sourceOf(d.Key)
+	b.deprecations = append(b.deprecations, d)
+}
+
+// unlock releases b.mu, then reports the deprecations found while it was
+// held. Methods that may merge or bind unlock with it.
+func (b *Builder) unlock() {
+	found, fn := b.deprecations, b.onDeprecated
+	b.deprecations = nil
+	b.mu.Unlock()
+
+	for _, d := range found {
+		if fn != nil {
+			fn(d)
+			continue
+		}
+		slog.Warn("deprecated configuration key", "key", d.Key, "replacement", d.Replacement, "source", d.Source, "message", d.Message)
+	}
+}
+
+// sourceOf returns the highest priority loaded source defining key.
+func (b *Builder) sourceOf(key string) string {
+	sources := b.sortedSources()
+	for i := len(sources) - 1; i >= 0; i-- {
+		if data, ok := b.mapSource[sources[i].Name()]; ok {
+			if _, ok := lookupPath(data, key); ok {
+				return sources[i].Name()
+			}
+		}
+	}
+	return ""
+}
+
+// applyBuilderAliases moves values under the keys of the builder alias map
+// to their replacements. The caller must hold b.mu.
+func (b *Builder) applyBuilderAliases(merged map[string]any) {
+	olds := make([]string, 0, len(b.aliases))
+	for old := range b.aliases {
+		olds = append(olds, old)
+	}
+	sort.Strings(olds)
+	for _, old := range olds {
+		b.resolveAlias(merged, "", b.aliases[old], old, "")
+	}
+}
+
+// applyTagAliases resolves the alias and deprecated tags of the struct bound
+// from data, returning a copy of data when it changes. The caller must hold
+// b.mu.
+func (b *Builder) applyTagAliases(data map[string]any, path string, typ reflect.Type) map[string]any {
+	plan := planFor(typ)
+	if len(plan.aliases) == 0 {
+		return data
+	}
+
+	data = deepCopyMap(data)
+	root, rootPath := data, path
+	// bindStruct binds the fields of a section named after the type
+	if section, ok := data[plan.section].(map[string]any); ok {
+		root, rootPath = section, joinKey(path, plan.section)
+	}
+
+	for _, ap := range plan.aliases {
+		if len(ap.aliases) == 0 {
+			if _, ok := lookupPath(root, ap.key); ok {
+				b.deprecated(Deprecation{Key: joinKey(rootPath, ap.key), Message: ap.deprecated})
+			}
+			continue
+		}
+		for _, alias := range ap.aliases {
+			b.resolveAlias(root, rootPath, ap.key, alias, ap.deprecated)
+		}
+	}
+	return data
+}
+
+// resolveAlias moves the value at alias in root to key, reporting the
+// deprecation and an *AliasConflictError when key already holds a
+// different value.
+func (b *Builder) resolveAlias(root map[string]any, rootPath, key, alias, message string) {
+	value, ok := lookupPath(root, alias)
+	if !ok {
+		return
+	}
+	deletePath(root, alias)
+	b.deprecated(Deprecation{Key: joinKey(rootPath, alias), Replacement: joinKey(rootPath, key), Message: message})
+
+	if current, ok := lookupPath(root, key); ok {
+		if !reflect.DeepEqual(current, value) {
+			b.errs = append(b.errs, &AliasConflictError{Key: joinKey(rootPath, key), Alias: joinKey(rootPath, alias)})
+		}
+		return
+	}
+	setPath(root, key, value)
+}
+
+func setPath(data map[string]any, key string, value any) {
+	parts := strings.Split(key, ".")
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := data[part].(map[string]any)
+		if !ok {
+			next = make(map[string]any)
+			data[part] = next
+		}
+		data = next
+	}
+	data[parts[len(parts)-1]] = value
+}
+
+// deletePath removes key from data, dropping maps it leaves empty.
+func deletePath(data map[string]any, key string) {
+	parent, last, found := strings.Cut(key, ".")
+	if !found {
+		delete(data, key)
+		return
+	}
+	if next, ok := data[parent].(map[string]any); ok {
+		deletePath(next, last)
+		if len(next) == 0 {
+			delete(data, parent)
+		}
+	}
+}
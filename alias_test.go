@@ -0,0 +1,137 @@
+package ascanius
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type aliasedMongo struct {
+	Host string `alias:"hostname,address" deprecated:"use host"`
+	Port int    `def:"27017"`
+}
+
+type aliasedConfig struct {
+	Mongo   aliasedMongo
+	Replica aliasedMongo
+	Verbose bool `deprecated:"set log.level to debug instead"`
+}
+
+func loadAliased(t *testing.T, data map[string]any, opts func(*Builder)) (aliasedConfig, *Builder, []Deprecation) {
+	t.Helper()
+	var got []Deprecation
+	b := New().
+		AddSource(&stubSource{name: "stub", priority: 1, data: data}).
+		OnDeprecated(func(d Deprecation) { got = append(got, d) })
+	if opts != nil {
+		opts(b)
+	}
+	var cfg aliasedConfig
+	b.Load(&cfg)
+	return cfg, b, got
+}
+
+func TestTagAliases(t *testing.T) {
+	cfg, b, deprecations := loadAliased(t, map[string]any{
+		"mongo":   map[string]any{"hostname": "db.internal", "port": 27018},
+		"replica": map[string]any{"address": "replica.internal"},
+	}, nil)
+	require.False(t, b.HasErrs(), b.Errs())
+
+	assert.Equal(t, "db.internal", cfg.Mongo.Host)
+	assert.Equal(t, 27018, cfg.Mongo.Port)
+	assert.Equal(t, "replica.internal", cfg.Replica.Host)
+	assert.Equal(t, []Deprecation{
+		{Key: "mongo.hostname", Replacement: "mongo.host", Source: "stub", Message: "use host"},
+		{Key: "replica.address", Replacement: "replica.host", Source: "stub", Message: "use host"},
+	}, deprecations)
+	assert.Equal(t, "mongo.hostname is deprecated (set in stub): use host", deprecations[0].String())
+
+	// the merged configuration is left untouched
+	_, ok := b.Lookup("mongo.host")
+	assert.False(t, ok)
+}
+
+func TestDeprecatedField(t *testing.T) {
+	cfg, b, deprecations := loadAliased(t, map[string]any{"verbose": true}, nil)
+	require.False(t, b.HasErrs(), b.Errs())
+
+	assert.True(t, cfg.Verbose)
+	assert.Equal(t, []Deprecation{{Key: "verbose", Source: "stub", Message: "set log.level to debug instead"}}, deprecations)
+}
+
+func TestAliasConflict(t *testing.T) {
+	tests := []struct {
+		name string
+		data map[string]any
+		err  bool
+	}{
+		{"same value", map[string]any{"mongo": map[string]any{"address": "a", "host": "a"}}, false},
+		{"different values", map[string]any{"mongo": map[string]any{"address": "a", "host": "b"}}, true},
+		{"different aliases", map[string]any{"mongo": map[string]any{"address": "a", "hostname": "b"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, b, _ := loadAliased(t, tt.data, nil)
+			if !tt.err {
+				assert.False(t, b.HasErrs(), b.Errs())
+				return
+			}
+			var conflict *AliasConflictError
+			require.True(t, errors.As(b.Err(), &conflict), b.Errs())
+			assert.Equal(t, "mongo.host", conflict.Key)
+		})
+	}
+}
+
+func TestBuilderAliases(t *testing.T) {
+	data := map[string]any{"database": map[string]any{"url": "mongodb://old"}}
+	cfg, b, deprecations := loadAliased(t, data, func(b *Builder) {
+		b.Aliases(map[string]string{"database.url": "mongo.host"})
+	})
+	require.False(t, b.HasErrs(), b.Errs())
+
+	assert.Equal(t, "mongodb://old", cfg.Mongo.Host)
+	value, ok := b.Lookup("mongo.host")
+	assert.True(t, ok)
+	assert.Equal(t, "mongodb://old", value)
+	_, ok = b.Lookup("database")
+	assert.False(t, ok, "empty parents of moved keys are dropped")
+	assert.Equal(t, []Deprecation{{Key: "database.url", Replacement: "mongo.host", Source: "stub"}}, deprecations)
+
+	data["mongo"] = map[string]any{"host": "mongodb://new"}
+	_, b, _ = loadAliased(t, data, func(b *Builder) {
+		b.Aliases(map[string]string{"database.url": "mongo.host"})
+	})
+	var conflict *AliasConflictError
+	assert.ErrorAs(t, b.Err(), &conflict)
+}
+
+func TestDeprecationLoggedByDefault(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	var cfg aliasedConfig
+	New().AddSource(&stubSource{name: "stub", priority: 1, data: map[string]any{"mongo": map[string]any{"address": "a"}}}).Load(&cfg)
+	assert.Contains(t, buf.String(), `level=WARN msg="deprecated configuration key" key=mongo.address replacement=mongo.host source=stub`)
+}
+
+func TestOnDeprecatedCallsBuilder(t *testing.T) {
+	b := New().AddSource(&stubSource{name: "stub", priority: 1, data: map[string]any{"verbose": true}})
+	var seen []any
+	b.OnDeprecated(func(d Deprecation) {
+		// the hook runs unlocked, so it may use the builder
+		value, _ := b.Lookup(d.Key)
+		seen = append(seen, value)
+	})
+
+	var cfg aliasedConfig
+	b.Load(&cfg)
+	assert.Equal(t, []any{true}, seen)
+}
@@ -0,0 +1,333 @@
+package ascanius
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// bindPlan holds what applyValues needs to know about a struct type, so
+// tags and snake_case names are computed once per type.
+type bindPlan struct {
+	// section is the snake_case type name, which may wrap the fields
+	section string
+	fields  []fieldPlan
+	// aliases holds the alias and deprecated tags of the fields and of
+	// nested structs
+	aliases []aliasPlan
+}
+
+type fieldPlan struct {
+	index  int
+	key    string
+	secret bool
+	// typ is the type values are converted to, the wrapped type for Secret fields
+	typ    reflect.Type
+	def    reflect.Value
+	hasDef bool
+}
+
+var bindPlans sync.Map // reflect.Type -> *bindPlan
+
+func planFor(typ reflect.Type) *bindPlan {
+	if plan, ok := bindPlans.Load(typ); ok {
+		return plan.(*bindPlan)
+	}
+
+	plan := &bindPlan{section: toSnakeCase(typ.Name())}
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		key := field.Tag.Get("cfg")
+		if key == "" {
+			key = toSnakeCase(field.Name)
+		}
+		fp := fieldPlan{index: i, key: key, secret: isSecretField(field), typ: field.Type}
+		if reflect.PointerTo(field.Type).Implements(secretHolderType) {
+			fp.typ = reflect.New(field.Type).Interface().(secretHolder).secretValue().Type()
+		}
+		if def := field.Tag.Get("def"); def != "" {
+			if parsed, err := parseDefault(def, fp.typ); err == nil {
+				fp.def, fp.hasDef = parsed, true
+			}
+		}
+		plan.fields = append(plan.fields, fp)
+	}
+
+	plan.aliases = collectAliases(plan, typ)
+
+	actual, _ := bindPlans.LoadOrStore(typ, plan)
+	return actual.(*bindPlan)
+}
+
+// defaultValue returns the parsed default, copying slices so that bound
+// structs never share the cached backing array.
+func (f *fieldPlan) defaultValue() reflect.Value {
+	if f.def.Kind() != reflect.Slice {
+		return f.def
+	}
+	out := reflect.MakeSlice(f.def.Type(), f.def.Len(), f.def.Len())
+	reflect.Copy(out, f.def)
+	return out
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// convertValue converts a decoded config value to targetType without a
+// JSON round trip. Integers are range checked, durations and
+// encoding.TextUnmarshaler types are parsed from strings, and containers
+// and structs are converted element by element.
+func convertValue(value any, targetType reflect.Type) (reflect.Value, error) {
+	if value == nil {
+		return reflect.Zero(targetType), nil
+	}
+	val := reflect.ValueOf(value)
+	if val.Type().AssignableTo(targetType) {
+		return val, nil
+	}
+	// e.g. a string to a named string type; conversions between kinds,
+	// such as int to string, are handled below
+	if val.Kind() == targetType.Kind() && val.Type().ConvertibleTo(targetType) {
+		return val.Convert(targetType), nil
+	}
+
+	ptr := reflect.PointerTo(targetType)
+	if s, ok := value.(string); ok && ptr.Implements(textUnmarshalerType) {
+		out := reflect.New(targetType)
+		if err := out.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return reflect.Value{}, err
+		}
+		return out.Elem(), nil
+	}
+	if ptr.Implements(jsonUnmarshalerType) {
+		return convertJson(value, targetType)
+	}
+
+	switch targetType.Kind() {
+	case reflect.String:
+		switch v := value.(type) {
+		case bool, int, int64, uint64, float64, json.Number:
+			return reflect.ValueOf(fmt.Sprint(v)).Convert(targetType), nil
+		case encoding.TextMarshaler:
+			// e.g. TOML local dates and times
+			text, err := v.MarshalText()
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			return reflect.ValueOf(string(text)).Convert(targetType), nil
+		}
+
+	case reflect.Bool:
+		if s, ok := value.(string); ok {
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			return reflect.ValueOf(b).Convert(targetType), nil
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s, ok := value.(string); ok && targetType == durationType {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			return reflect.ValueOf(d), nil
+		}
+		n, err := toInt64(value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		out := reflect.New(targetType).Elem()
+		if out.OverflowInt(n) {
+			return reflect.Value{}, fmt.Errorf("%d overflows %s", n, targetType)
+		}
+		out.SetInt(n)
+		return out, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := toUint64(value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		out := reflect.New(targetType).Elem()
+		if out.OverflowUint(n) {
+			return reflect.Value{}, fmt.Errorf("%d overflows %s", n, targetType)
+		}
+		out.SetUint(n)
+		return out, nil
+
+	case reflect.Float32, reflect.Float64:
+		f, err := toFloat64(value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		out := reflect.New(targetType).Elem()
+		if out.OverflowFloat(f) {
+			return reflect.Value{}, fmt.Errorf("%g overflows %s", f, targetType)
+		}
+		out.SetFloat(f)
+		return out, nil
+
+	case reflect.Slice:
+		if items, ok := value.([]any); ok {
+			out := reflect.MakeSlice(targetType, len(items), len(items))
+			for i, item := range items {
+				elem, err := convertValue(item, targetType.Elem())
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("[%d]: %w", i, err)
+				}
+				out.Index(i).Set(elem)
+			}
+			return out, nil
+		}
+
+	case reflect.Array:
+		if items, ok := value.([]any); ok {
+			if len(items) > targetType.Len() {
+				return reflect.Value{}, fmt.Errorf("%d items do not fit in %s", len(items), targetType)
+			}
+			out := reflect.New(targetType).Elem()
+			for i, item := range items {
+				elem, err := convertValue(item, targetType.Elem())
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("[%d]: %w", i, err)
+				}
+				out.Index(i).Set(elem)
+			}
+			return out, nil
+		}
+
+	case reflect.Map:
+		if m, ok := value.(map[string]any); ok {
+			out := reflect.MakeMapWithSize(targetType, len(m))
+			for k, v := range m {
+				key, err := convertValue(k, targetType.Key())
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("key %s: %w", k, err)
+				}
+				elem, err := convertValue(v, targetType.Elem())
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("%s: %w", k, err)
+				}
+				out.SetMapIndex(key, elem)
+			}
+			return out, nil
+		}
+
+	case reflect.Struct:
+		if m, ok := value.(map[string]any); ok {
+			out := reflect.New(targetType).Elem()
+			if errs := bindStruct(out, m, "", nil); len(errs) > 0 {
+				return reflect.Value{}, errs[0]
+			}
+			return out, nil
+		}
+
+	case reflect.Pointer:
+		elem, err := convertValue(value, targetType.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		out := reflect.New(targetType.Elem())
+		out.Elem().Set(elem)
+		return out, nil
+	}
+
+	return reflect.Value{}, fmt.Errorf("cannot convert %T to %s", value, targetType)
+}
+
+// convertJson is the JSON round trip, kept for types with their own
+// UnmarshalJSON.
+func convertJson(value any, targetType reflect.Type) (reflect.Value, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	ptr := reflect.New(targetType)
+	if err := json.Unmarshal(raw, ptr.Interface()); err != nil {
+		return reflect.Value{}, err
+	}
+	return ptr.Elem(), nil
+}
+
+func toInt64(value any) (int64, error) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, fmt.Errorf("%d overflows int64", v)
+		}
+		return int64(v), nil
+	case float64:
+		if v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+			return 0, fmt.Errorf("%v is not an integer", v)
+		}
+		return int64(v), nil
+	case json.Number:
+		return v.Int64()
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	default:
+		return 0, fmt.Errorf("cannot convert %T to an integer", value)
+	}
+}
+
+func toUint64(value any) (uint64, error) {
+	switch v := value.(type) {
+	case uint64:
+		return v, nil
+	case json.Number:
+		return strconv.ParseUint(v.String(), 10, 64)
+	case string:
+		return strconv.ParseUint(v, 10, 64)
+	case float64:
+		if v != math.Trunc(v) || v < 0 || v >= math.MaxUint64 {
+			return 0, fmt.Errorf("%v is not an unsigned integer", v)
+		}
+		return uint64(v), nil
+	}
+	n, err := toInt64(value)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("%d is negative", n)
+	}
+	return uint64(n), nil
+}
+
+func toFloat64(value any) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case json.Number:
+		return v.Float64()
+	case string:
+		return strconv.ParseFloat(v, 64)
+	default:
+		return 0, fmt.Errorf("cannot convert %T to a float", value)
+	}
+}
@@ -0,0 +1,143 @@
+package ascanius
+
+import (
+	"math"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type logLevel string
+
+type endpoint struct {
+	Host string
+	Port uint16 `def:"80"`
+}
+
+func TestConvertValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  any
+		target any
+		want   any
+	}{
+		{"int to uint16", 8080, uint16(0), uint16(8080)},
+		{"integral float to int", float64(3), 0, 3},
+		{"big uint64", uint64(math.MaxUint64), uint64(0), uint64(math.MaxUint64)},
+		{"int64 to int8", int64(-128), int8(0), int8(-128)},
+		{"named string", "debug", logLevel(""), logLevel("debug")},
+		{"number to string", 8080, "", "8080"},
+		{"duration", "1m30s", time.Duration(0), 90 * time.Second},
+		{"text unmarshaler", "10.0.0.1", net.IP{}, net.ParseIP("10.0.0.1")},
+		{"string slice", []any{"a", "b"}, []string{}, []string{"a", "b"}},
+		{"array", []any{1, 2}, [2]int{}, [2]int{1, 2}},
+		{"map", map[string]any{"a": 1, "b": 2}, map[string]int{}, map[string]int{"a": 1, "b": 2}},
+		{"pointer", 5, (*int)(nil), func() *int { n := 5; return &n }()},
+		{
+			"slice of structs",
+			[]any{map[string]any{"host": "a", "port": 1}, map[string]any{"host": "b"}},
+			[]endpoint{},
+			[]endpoint{{"a", 1}, {"b", 80}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := convertValue(tt.value, reflect.TypeOf(tt.target))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.Interface())
+		})
+	}
+}
+
+func TestConvertValueErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  any
+		target any
+	}{
+		{"overflow", 70000, uint16(0)},
+		{"negative to uint", -1, uint(0)},
+		{"fractional to int", 1.5, 0},
+		{"uint64 to int64", uint64(math.MaxUint64), int64(0)},
+		{"bad duration", "soon", time.Duration(0)},
+		{"map to slice", map[string]any{}, []string{}},
+		{"bad element", []any{"a", true}, []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := convertValue(tt.value, reflect.TypeOf(tt.target))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestBindPlanCached(t *testing.T) {
+	typ := reflect.TypeOf(AppConfig{})
+	assert.Same(t, planFor(typ), planFor(typ))
+}
+
+func TestDefaultSlicesNotShared(t *testing.T) {
+	type config struct {
+		Hosts []string `def:"a,b"`
+	}
+	var first, second config
+	New().Load(&first)
+	New().Load(&second)
+
+	first.Hosts[0] = "changed"
+	assert.Equal(t, []string{"a", "b"}, second.Hosts)
+}
+
+type generatedConfig struct {
+	Host  string
+	Token string
+}
+
+func TestRegisteredBinderPreferred(t *testing.T) {
+	calls := 0
+	RegisterBinder(func(cfg *generatedConfig, data map[string]any, path string) error {
+		calls++
+		host, err := ConvertString[string](data["host"])
+		if err != nil {
+			return NewBindError[string](path, "host", err)
+		}
+		cfg.Host = "generated:" + host
+		return nil
+	}, "token")
+	t.Cleanup(func() { binders.Delete(reflect.TypeFor[generatedConfig]()) })
+
+	var cfg generatedConfig
+	b := New().AddSource(&stubSource{name: "stub", priority: 1, data: map[string]any{"host": "db", "token": "abc"}}).Load(&cfg)
+	require.False(t, b.HasErrs(), b.Errs())
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, "generated:db", cfg.Host)
+	assert.True(t, b.IsSecret("token"))
+}
+
+func TestBindLargeIntegers(t *testing.T) {
+	type config struct {
+		Id    int64
+		Ratio float64
+	}
+
+	var fromJson config
+	path := writeTemp(t, "config.json", `{"id": 9007199254740993, "ratio": 0.5}`)
+	b := New().Source(path, 1).Load(&fromJson)
+	require.False(t, b.HasErrs(), b.Errs())
+	assert.Equal(t, int64(9007199254740993), fromJson.Id)
+	assert.Equal(t, 0.5, fromJson.Ratio)
+
+	var fromEnv config
+	t.Setenv("APP__ID", "9007199254740993")
+	t.Setenv("APP__RATIO", "0.5")
+	b = New().Source("env", 1).Load(&fromEnv)
+	require.False(t, b.HasErrs(), b.Errs())
+	assert.Equal(t, int64(9007199254740993), fromEnv.Id)
+	assert.Equal(t, 0.5, fromEnv.Ratio)
+}
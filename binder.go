@@ -0,0 +1,172 @@
+package ascanius
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// registeredBinder is a generated binder registered with RegisterBinder.
+type registeredBinder struct {
+	bind       func(target any, data map[string]any, path string) error
+	secretKeys []string
+}
+
+var binders sync.Map // reflect.Type -> *registeredBinder
+
+// RegisterBinder registers a generated binder for T, used by the builder
+// instead of reflection whenever it loads into a *T. secretKeys are the key
+// paths, relative to T, of fields holding secrets. It is called from the
+// init function of files written by ascanius-gen.
+func RegisterBinder[T any](bind func(target *T, data map[string]any, path string) error, secretKeys ...string) {
+	binders.Store(reflect.TypeFor[T](), &registeredBinder{
+		bind: func(target any, data map[string]any, path string) error {
+			return bind(target.(*T), data, path)
+		},
+		secretKeys: secretKeys,
+	})
+}
+
+func binderFor(typ reflect.Type) (*registeredBinder, bool) {
+	b, ok := binders.Load(typ)
+	if !ok {
+		return nil, false
+	}
+	return b.(*registeredBinder), true
+}
+
+// SnakeCase returns the key a field name binds to when it has no cfg tag.
+func SnakeCase(name string) string {
+	return toSnakeCase(name)
+}
+
+// JoinKey joins a parent key path and a key with a dot.
+func JoinKey(parent, key string) string {
+	return joinKey(parent, key)
+}
+
+// ParseDefault parses a `def` tag value for T the way Load does. ok is
+// false when T has no default syntax or def is invalid.
+func ParseDefault[T any](def string) (value T, ok bool) {
+	parsed, err := parseDefault(def, reflect.TypeFor[T]())
+	if err != nil {
+		return value, false
+	}
+	return parsed.Interface().(T), true
+}
+
+// NewBindError returns a *BindError for the key below path holding a T.
+func NewBindError[T any](path, key string, err error) error {
+	return &BindError{Key: joinKey(path, key), Type: reflect.TypeFor[T](), Err: err}
+}
+
+// Convert converts a decoded config value to T the way Load does. Generated
+// binders use it for types without a dedicated conversion.
+func Convert[T any](value any) (T, error) {
+	var out T
+	val, err := convertValue(value, reflect.TypeFor[T]())
+	if err != nil {
+		return out, err
+	}
+	return val.Interface().(T), nil
+}
+
+// ConvertString converts strings and scalars to a string type.
+func ConvertString[T ~string](value any) (T, error) {
+	switch v := value.(type) {
+	case string:
+		return T(v), nil
+	case bool, int, int64, uint64, float64:
+		return T(fmt.Sprint(v)), nil
+	}
+	return Convert[T](value)
+}
+
+// ConvertBool converts booleans and strings such as "true" to a bool type.
+func ConvertBool[T ~bool](value any) (T, error) {
+	switch v := value.(type) {
+	case bool:
+		return T(v), nil
+	case string:
+		b, err := strconv.ParseBool(v)
+		return T(b), err
+	}
+	return *new(T), fmt.Errorf("cannot convert %T to %T", value, *new(T))
+}
+
+// ConvertInt converts integers, integral floats and numeric strings to an
+// integer type, failing when the value does not fit.
+func ConvertInt[T ~int | ~int8 | ~int16 | ~int32 | ~int64](value any) (T, error) {
+	n, err := toInt64(value)
+	if err != nil {
+		return 0, err
+	}
+	if int64(T(n)) != n {
+		return 0, fmt.Errorf("%d overflows %T", n, T(0))
+	}
+	return T(n), nil
+}
+
+// ConvertUint is ConvertInt for unsigned integer types.
+func ConvertUint[T ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64](value any) (T, error) {
+	n, err := toUint64(value)
+	if err != nil {
+		return 0, err
+	}
+	if uint64(T(n)) != n {
+		return 0, fmt.Errorf("%d overflows %T", n, T(0))
+	}
+	return T(n), nil
+}
+
+// ConvertFloat converts numbers and numeric strings to a float type.
+func ConvertFloat[T ~float32 | ~float64](value any) (T, error) {
+	f, err := toFloat64(value)
+	if err != nil {
+		return 0, err
+	}
+	if !math.IsInf(f, 0) && math.IsInf(float64(T(f)), 0) {
+		return 0, fmt.Errorf("%g overflows %T", f, T(0))
+	}
+	return T(f), nil
+}
+
+// ConvertDuration converts strings such as "1m30s" and nanosecond counts
+// to a time.Duration.
+func ConvertDuration(value any) (time.Duration, error) {
+	if s, ok := value.(string); ok {
+		return time.ParseDuration(s)
+	}
+	return ConvertInt[time.Duration](value)
+}
+
+// ConvertStringSlice converts a list of scalars to a []string.
+func ConvertStringSlice(value any) ([]string, error) {
+	items, ok := value.([]any)
+	if !ok {
+		return Convert[[]string](value)
+	}
+	out := make([]string, len(items))
+	for i, item := range items {
+		s, err := ConvertString[string](item)
+		if err != nil {
+			return nil, fmt.Errorf("[%d]: %w", i, err)
+		}
+		out[i] = s
+	}
+	return out, nil
+}
+
+// joinErrs flattens an error returned by a generated binder.
+func joinErrs(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
@@ -1,34 +1,79 @@
 package ascanius
 
 import (
-	"encoding/json"
+	"context"
+	"encoding"
 	"errors"
 	"fmt"
+	"maps"
 	"path/filepath"
 	"reflect"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 	"unicode"
 )
 
 const (
 	DEFAULT_ENV_SEPARATOR = "__"
 	DEFAULT_ENV_PREFIX    = "APP"
+	DEFAULT_CONCURRENCY   = 8
 	JSON_EXTENSION        = ".json"
 	TOML_EXTENSION        = ".toml"
 	DOTENV_EXTENSION      = ".env"
+	INI_EXTENSION         = ".ini"
+	PROPERTIES_EXTENSION  = ".properties"
 	ENV                   = "env"
 )
 
-var YAML_EXTENSIONS = []string{".yaml", ".yml"}
+var (
+	YAML_EXTENSIONS  = []string{".yaml", ".yml"}
+	JSON5_EXTENSIONS = []string{".jsonc", ".json5"}
+)
 
 type Builder struct {
+	mu sync.Mutex
+
 	sources   []Source
 	mapSource map[string]map[string]any
+	merged    map[string]any
 	errs      []error
-	envPrefix string
-	envSep    string
+	// configErrs are setup errors, kept across loads
+	configErrs []error
+	envPrefix  string
+	envSep     string
+	// profile selects the documents of multi-document YAML files
+	profile string
+
+	secretPatterns []string
+	secretPaths    map[string]bool
+	revealSecrets  bool
+
+	sourceSchemas map[string][]*SchemaValidator
+	mergedSchemas []*SchemaValidator
+
+	keyProviders []KeyProvider
+
+	resolvers     map[string]SecretResolver
+	secretTimeout time.Duration
+	keepRefs      bool
+
+	loadTimeout    time.Duration
+	sourceTimeouts map[string]time.Duration
+	concurrency    int
+
+	// aliases maps deprecated key paths to their replacements
+	aliases      map[string]string
+	onDeprecated func(Deprecation)
+	// deprecations found while b.mu is held, reported by unlock
+	deprecations []Deprecation
+
+	migrations []Migration
+
+	// writeTarget is the name of the source Set writes to
+	writeTarget string
 }
 
 func New() *Builder {
@@ -37,19 +82,48 @@ func New() *Builder {
 		mapSource: make(map[string]map[string]any),
 		envPrefix: DEFAULT_ENV_PREFIX,
 		envSep:    DEFAULT_ENV_SEPARATOR,
+
+		secretPatterns: append([]string{}, DEFAULT_SECRET_PATTERNS...),
+		secretPaths:    make(map[string]bool),
+
+		sourceSchemas: make(map[string][]*SchemaValidator),
+
+		resolvers: map[string]SecretResolver{
+			"file": FileResolver{},
+			"env":  EnvResolver{},
+		},
+		secretTimeout: DEFAULT_SECRET_TIMEOUT,
+
+		sourceTimeouts: make(map[string]time.Duration),
+		concurrency:    DEFAULT_CONCURRENCY,
+
+		aliases: make(map[string]string),
 	}
 }
 
 func (b *Builder) EnvPrefix(prefix string) *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 	b.envPrefix = prefix
 	return b
 }
 
 func (b *Builder) EnvSeparator(sep string) *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 	b.envSep = sep
 	return b
 }
 
+// Profile selects, in the multi-document YAML files added after it, the
+// documents whose profile key names profile. See WithYamlProfile.
+func (b *Builder) Profile(profile string) *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.profile = profile
+	return b
+}
+
 func hasSuffixIn(s string, suffixes ...string) bool {
 	for _, suffix := range suffixes {
 		if strings.HasSuffix(s, suffix) {
@@ -60,208 +134,492 @@ func hasSuffixIn(s string, suffixes ...string) bool {
 }
 
 func (b *Builder) Source(name string, priority int) *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// the type is picked case-insensitively, but file sources get the path
+	// as given: a lowercased path does not exist on case-sensitive file
+	// systems
 	nameLower := strings.ToLower(name)
 	base := filepath.Base(nameLower)
 	switch {
+	case strings.HasPrefix(nameLower, "http://") || strings.HasPrefix(nameLower, "https://"):
+		b.sources = append(b.sources, NewHttpSource(name, "", priority, WithHttpProfile(b.profile)))
+
 	case nameLower == ENV:
 		b.sources = append(b.sources, NewEnvSource(ENV, priority, WithPrefix(b.envPrefix), WithSeparator(b.envSep)))
 
 	case strings.HasPrefix(base, DOTENV_EXTENSION):
-		b.sources = append(b.sources, NewEnvSource(nameLower, priority, WithPrefix(b.envPrefix), WithSeparator(b.envSep)))
+		b.sources = append(b.sources, NewEnvSource(name, priority, WithPrefix(b.envPrefix), WithSeparator(b.envSep)))
 
 	case strings.HasSuffix(base, JSON_EXTENSION):
-		b.sources = append(b.sources, NewJsonSource(nameLower, "", priority))
+		b.sources = append(b.sources, NewJsonSource(name, "", priority))
+
+	case hasSuffixIn(base, JSON5_EXTENSIONS...):
+		b.sources = append(b.sources, NewJson5Source(name, "", priority))
 
 	case strings.HasSuffix(base, TOML_EXTENSION):
-		b.sources = append(b.sources, NewTomlSource(nameLower, "", priority))
+		b.sources = append(b.sources, NewTomlSource(name, "", priority))
 
 	case hasSuffixIn(base, YAML_EXTENSIONS...):
-		b.sources = append(b.sources, NewYamlSource(nameLower, "", priority))
+		b.sources = append(b.sources, NewYamlSource(name, "", priority, WithYamlProfile(b.profile)))
+
+	case strings.HasSuffix(base, INI_EXTENSION):
+		b.sources = append(b.sources, NewIniSource(name, "", priority))
+
+	case strings.HasSuffix(base, PROPERTIES_EXTENSION):
+		b.sources = append(b.sources, NewPropertiesSource(name, "", priority))
 
 	case !strings.Contains(name, "."):
-		b.errs = append(b.errs, fmt.Errorf("no source type provided for %s", name))
+		b.configErr(fmt.Errorf("no source type provided for %s", name))
 
 	default:
-		b.errs = append(b.errs, fmt.Errorf("unsupported source type for %s", name))
+		b.configErr(fmt.Errorf("unsupported source type for %s", name))
 	}
 
 	return b
 }
 
-func (b *Builder) LoadSection(target any, section string) *Builder {
-	var err error
-	if target == nil {
-		b.errs = append(b.errs, errors.New("target cannot be nil"))
-		return b
+// configErr records a mistake in the builder setup. Unlike load errors it is
+// reported again after every load.
+func (b *Builder) configErr(err error) {
+	b.configErrs = append(b.configErrs, err)
+	b.errs = append(b.errs, err)
+}
+
+// LoadTimeout sets how long each source may take to load, unless it has its
+// own SourceTimeout. Zero, the default, means no limit.
+func (b *Builder) LoadTimeout(d time.Duration) *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.loadTimeout = d
+	return b
+}
+
+// SourceTimeout sets how long the source called name may take to load.
+func (b *Builder) SourceTimeout(name string, d time.Duration) *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sourceTimeouts[name] = d
+	return b
+}
+
+func (b *Builder) loadSource(ctx context.Context, src Source) (map[string]any, error) {
+	name := src.Name()
+	timeout, ok := b.sourceTimeouts[name]
+	if !ok {
+		timeout = b.loadTimeout
+	}
+
+	loadCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		loadCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	data, err := AdaptContext(src).LoadContext(loadCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(loadCtx.Err(), context.DeadlineExceeded) {
+		return data, fmt.Errorf("timed out after %s: %w", timeout, err)
 	}
+	return data, err
+}
+
+// Concurrency sets how many sources are loaded at the same time.
+func (b *Builder) Concurrency(n int) *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if n < 1 {
+		n = 1
+	}
+	b.concurrency = n
+	return b
+}
 
-	sort.SliceStable(b.sources, func(i, j int) bool {
-		return b.sources[i].Priority() < b.sources[j].Priority()
+type loadResult struct {
+	data map[string]any
+	errs []error
+	// skip is set when the source produced no usable data
+	skip bool
+	// cache is set when data can be reused by later loads
+	cache bool
+}
+
+// load reads, normalizes, migrates and validates a single uncached source. It runs on
+// a worker while the merging goroutine holds b.mu, so it only reads the
+// builder configuration.
+func (b *Builder) load(ctx context.Context, src Source) loadResult {
+	name := src.Name()
+	if err := ctx.Err(); err != nil {
+		return loadResult{errs: []error{&SourceError{Source: name, Err: fmt.Errorf("loading canceled: %w", err)}}, skip: true}
+	}
+
+	var res loadResult
+	loaded, err := b.loadSource(ctx, src)
+	if err != nil {
+		res.errs = append(res.errs, &SourceError{Source: name, Err: err})
+		// a source may still return usable data alongside the error,
+		// e.g. a remote source falling back to its last known good copy
+		if loaded == nil {
+			res.skip = true
+			return res
+		}
+	}
+	res.data = normalizeKeysToSnakeCase(loaded)
+	if len(b.migrations) > 0 {
+		if _, err := migrate(res.data, b.migrations); err != nil {
+			res.errs = append(res.errs, &SourceError{Source: name, Err: err})
+			res.skip = true
+			return res
+		}
+	}
+	if errs := b.validateSource(name, res.data); len(errs) > 0 {
+		res.errs = append(res.errs, errs...)
+		res.skip = true
+		return res
+	}
+	res.cache = err == nil
+	return res
+}
+
+// loadAll loads the uncached sources with a bounded pool of workers. Results
+// keep the order of sources.
+func (b *Builder) loadAll(ctx context.Context, sources []Source) []loadResult {
+	results := make([]loadResult, len(sources))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(b.concurrency, len(sources)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = b.load(ctx, sources[i])
+			}
+		}()
+	}
+
+	for i, src := range sources {
+		if data, ok := b.mapSource[src.Name()]; ok {
+			results[i] = loadResult{data: data}
+			continue
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// sortedSources returns the sources in priority order without reordering
+// b.sources.
+func (b *Builder) sortedSources() []Source {
+	sources := append([]Source{}, b.sources...)
+	sort.SliceStable(sources, func(i, j int) bool {
+		return sources[i].Priority() < sources[j].Priority()
 	})
+	return sources
+}
 
+// merge loads and merges every source. Errors of the previous load are
+// dropped. The caller must hold b.mu.
+func (b *Builder) merge(ctx context.Context) map[string]any {
+	b.errs = append([]error{}, b.configErrs...)
+	sources := b.sortedSources()
 	merged := make(map[string]any)
 
-	for _, src := range b.sources {
-		name := src.Name()
+	// errors are reported in priority order whichever source finished first
+	for i, res := range b.loadAll(ctx, sources) {
+		b.errs = append(b.errs, res.errs...)
+		if res.skip {
+			continue
+		}
+		if res.cache {
+			b.mapSource[sources[i].Name()] = res.data
+		}
+		merged = mergeMaps(merged, deepCopyMap(res.data))
+	}
 
-		var data map[string]any
-		if cached, ok := b.mapSource[name]; ok {
-			data = cached
+	b.decryptValues(merged, "")
+	if !b.keepRefs {
+		b.resolveRefs(ctx, merged, "", make(refCache))
+	}
+	b.applyBuilderAliases(merged)
+	b.errs = append(b.errs, b.validateMerged(merged)...)
+	b.merged = merged
+	return merged
+}
+
+// current returns the last merged configuration, merging first if nothing
+// has been loaded yet. The caller must hold b.mu.
+func (b *Builder) current() map[string]any {
+	if b.merged == nil {
+		b.merge(context.Background())
+	}
+	return b.merged
+}
+
+// Poll polls src, which must be registered with the builder, like
+// HttpSource.Poll. A new version drops the cached copy of src so that the
+// next load uses it, and a failed request is reported in Errs as a
+// *SourceError until the next load. onChange may be nil.
+func (b *Builder) Poll(ctx context.Context, src *HttpSource, interval time.Duration, onChange func(map[string]any, error)) {
+	src.Poll(ctx, interval, func(data map[string]any, err error) {
+		b.mu.Lock()
+		if err != nil {
+			b.errs = append(b.errs, &SourceError{Source: src.Name(), Err: err})
 		} else {
-			data, err = src.Load()
-			if err != nil {
-				b.errs = append(b.errs, err)
-				continue
-			}
-			data = normalizeKeysToSnakeCase(data)
-			b.mapSource[name] = data
+			delete(b.mapSource, src.Name())
+			b.merged = nil
+		}
+		b.mu.Unlock()
+
+		if onChange != nil {
+			onChange(data, err)
 		}
+	})
+}
+
+// AddSource registers a source created by the caller, e.g. one built with options.
+func (b *Builder) AddSource(src Source) *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sources = append(b.sources, src)
+	return b
+}
 
-		merged = mergeMaps(merged, data)
+// Invalidate drops the cached data of the source called name, so that the
+// next load reads it again.
+func (b *Builder) Invalidate(name string) *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.mapSource, name)
+	b.merged = nil
+	return b
+}
+
+// Reload drops the cached data of every source, then loads and merges them
+// again. Errs reports the errors of this load only.
+func (b *Builder) Reload() *Builder {
+	return b.ReloadContext(context.Background())
+}
+
+// ReloadContext is Reload with loading bound to ctx.
+func (b *Builder) ReloadContext(ctx context.Context) *Builder {
+	b.mu.Lock()
+	defer b.unlock()
+	clear(b.mapSource)
+	b.merge(ctx)
+	return b
+}
+
+// Clone returns a builder with the same configuration, sources and cached
+// source data, which can be extended without affecting b. Sources are
+// shared, not copied.
+func (b *Builder) Clone() *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := &Builder{
+		sources:    append([]Source{}, b.sources...),
+		mapSource:  maps.Clone(b.mapSource),
+		configErrs: append([]error{}, b.configErrs...),
+		envPrefix:  b.envPrefix,
+		envSep:     b.envSep,
+		profile:    b.profile,
+
+		secretPatterns: append([]string{}, b.secretPatterns...),
+		secretPaths:    maps.Clone(b.secretPaths),
+		revealSecrets:  b.revealSecrets,
+
+		sourceSchemas: make(map[string][]*SchemaValidator, len(b.sourceSchemas)),
+		mergedSchemas: append([]*SchemaValidator{}, b.mergedSchemas...),
+
+		keyProviders: append([]KeyProvider{}, b.keyProviders...),
+
+		resolvers:     maps.Clone(b.resolvers),
+		secretTimeout: b.secretTimeout,
+		keepRefs:      b.keepRefs,
+
+		loadTimeout:    b.loadTimeout,
+		sourceTimeouts: maps.Clone(b.sourceTimeouts),
+		concurrency:    b.concurrency,
+
+		aliases:      maps.Clone(b.aliases),
+		onDeprecated: b.onDeprecated,
+
+		migrations: append([]Migration{}, b.migrations...),
+
+		writeTarget: b.writeTarget,
+	}
+	c.errs = append([]error{}, c.configErrs...)
+	for name, schemas := range b.sourceSchemas {
+		c.sourceSchemas[name] = append([]*SchemaValidator{}, schemas...)
+	}
+	return c
+}
+
+func (b *Builder) LoadSection(target any, section string) *Builder {
+	return b.LoadSectionContext(context.Background(), target, section)
+}
+
+// LoadSectionContext is LoadSection with loading bound to ctx.
+func (b *Builder) LoadSectionContext(ctx context.Context, target any, section string) *Builder {
+	b.mu.Lock()
+	defer b.unlock()
+
+	if target == nil {
+		b.errs = append(append([]error{}, b.configErrs...), &BindError{Err: errors.New("target cannot be nil")})
+		return b
 	}
 
+	merged := b.merge(ctx)
+
 	sectionKey := toSnakeCase(section)
 	if sectionData, ok := merged[sectionKey]; ok {
 		if sectionMap, ok := sectionData.(map[string]any); ok {
-			err := b.applyValues(target, sectionMap)
+			err := b.applyValues(target, sectionMap, sectionKey)
 			if err != nil {
 				b.errs = append(b.errs, err)
 			}
 			return b
 		}
 	}
-	b.errs = append(b.errs, fmt.Errorf("section '%s' not found", sectionKey))
+	b.errs = append(b.errs, &SectionNotFoundError{Section: sectionKey})
 	return b
 }
 
 func (b *Builder) Load(target any) *Builder {
-	var err error
+	return b.LoadContext(context.Background(), target)
+}
+
+// LoadContext is Load with loading bound to ctx: once ctx is done the
+// remaining sources and secret references are not waited for.
+func (b *Builder) LoadContext(ctx context.Context, target any) *Builder {
+	b.mu.Lock()
+	defer b.unlock()
+
 	if target == nil {
-		b.errs = append(b.errs, errors.New("target cannot be nil"))
+		b.errs = append(append([]error{}, b.configErrs...), &BindError{Err: errors.New("target cannot be nil")})
 		return b
 	}
 
-	sort.SliceStable(b.sources, func(i, j int) bool {
-		return b.sources[i].Priority() < b.sources[j].Priority()
-	})
-
-	merged := make(map[string]any)
-
-	for _, src := range b.sources {
-		name := src.Name()
-
-		var data map[string]any
-		if cached, ok := b.mapSource[name]; ok {
-			data = cached
-		} else {
-			data, err = src.Load()
-			if err != nil {
-				b.errs = append(b.errs, err)
-				continue
-			}
-			data = normalizeKeysToSnakeCase(data)
-			b.mapSource[name] = data
-		}
-
-		merged = mergeMaps(merged, data)
-	}
+	merged := b.merge(ctx)
 
-	err = b.applyValues(target, merged)
+	err := b.applyValues(target, merged, "")
 	if err != nil {
 		b.errs = append(b.errs, err)
 	}
 	return b
 }
 
-func (b *Builder) applyValues(target any, data map[string]any) error {
+func (b *Builder) applyValues(target any, data map[string]any, path string) error {
 	val := reflect.ValueOf(target)
 	if val.Kind() != reflect.Ptr || val.IsNil() {
-		return errors.New("target must be a non-nil pointer to a struct")
+		return &BindError{Err: errors.New("target must be a non-nil pointer to a struct")}
 	}
 
 	val = val.Elem()
 	if val.Kind() != reflect.Struct {
-		return errors.New("target must point to a struct")
+		return &BindError{Err: errors.New("target must point to a struct")}
 	}
 
-	typ := val.Type()
-	structNameKey := toSnakeCase(typ.Name())
-	if sectionData, ok := data[structNameKey]; ok {
-		if sectionMap, ok := sectionData.(map[string]any); ok {
-			return b.applyValues(target, sectionMap)
+	data = b.applyTagAliases(data, path, val.Type())
+
+	if binder, ok := binderFor(val.Type()); ok {
+		for _, key := range binder.secretKeys {
+			b.secretPaths[joinKey(path, key)] = true
 		}
+		b.errs = append(b.errs, joinErrs(binder.bind(target, data, path))...)
+		return nil
 	}
 
-	for i := range typ.NumField() {
-		field := typ.Field(i)
-		fieldVal := val.Field(i)
-		if !fieldVal.CanSet() {
-			continue
+	b.errs = append(b.errs, bindStruct(val, data, path, b.secretPaths)...)
+	return nil
+}
+
+// bindStruct stores data in the struct val using the cached plan of its
+// type and returns a *BindError for every value that cannot be converted.
+// Secret key paths are recorded in secrets when it is not nil.
+func bindStruct(val reflect.Value, data map[string]any, path string, secrets map[string]bool) []error {
+	plan := planFor(val.Type())
+	if sectionData, ok := data[plan.section]; ok {
+		if sectionMap, ok := sectionData.(map[string]any); ok {
+			return bindStruct(val, sectionMap, joinKey(path, plan.section), secrets)
 		}
+	}
+
+	var errs []error
+	for i := range plan.fields {
+		field := &plan.fields[i]
+		fieldVal := val.Field(field.index)
+		key := joinKey(path, field.key)
 
-		cfgTag := field.Tag.Get("cfg")
-		if cfgTag == "" {
-			cfgTag = toSnakeCase(field.Name)
+		if field.secret {
+			if secrets != nil {
+				secrets[key] = true
+			}
+			if inner, ok := secretValue(fieldVal); ok {
+				fieldVal = inner
+			}
 		}
 
-		value, exists := data[cfgTag]
+		value, exists := data[field.key]
 		if !exists {
-			if defVal := field.Tag.Get("def"); defVal != "" {
-				if parsedVal, err := parseDefault(defVal, fieldVal.Type()); err == nil {
-					fieldVal.Set(parsedVal)
-				}
+			if field.hasDef {
+				fieldVal.Set(field.defaultValue())
 			}
 			continue
 		}
 
 		if fieldVal.Kind() == reflect.Struct {
 			if subMap, ok := value.(map[string]any); ok {
-				if err := b.applyValues(fieldVal.Addr().Interface(), subMap); err != nil {
-					return fmt.Errorf("error in section %s: %w", cfgTag, err)
-				}
+				errs = append(errs, bindStruct(fieldVal, subMap, key, secrets)...)
 				continue
 			}
 		}
 
-		if parsed, err := convertValue(value, fieldVal.Type()); err == nil {
-			fieldVal.Set(parsed)
+		if value == nil {
+			continue
+		}
+		parsed, err := convertValue(value, field.typ)
+		if err != nil {
+			errs = append(errs, &BindError{Key: key, Type: field.typ, Err: err})
+			continue
 		}
+		fieldVal.Set(parsed)
 	}
-	return nil
+	return errs
 }
 
-func convertValue(value any, targetType reflect.Type) (reflect.Value, error) {
-	val := reflect.ValueOf(value)
-
-	if val.Type().AssignableTo(targetType) {
-		return val, nil
-	}
-
-	if val.Type().ConvertibleTo(targetType) {
-		return val.Convert(targetType), nil
-	}
-
-	b, err := json.Marshal(value)
-	if err != nil {
-		return reflect.Value{}, err
+// parseDefault parses a def tag for t. Durations are written like "5s" or
+// in nanoseconds, and other types are parsed with their UnmarshalText
+// method when their kind has no default syntax.
+func parseDefault(def string, t reflect.Type) (reflect.Value, error) {
+	if t == durationType {
+		if d, err := time.ParseDuration(def); err == nil {
+			return reflect.ValueOf(d), nil
+		}
 	}
-
-	ptr := reflect.New(targetType)
-	if err := json.Unmarshal(b, ptr.Interface()); err != nil {
-		return reflect.Value{}, err
+	v, err := parseKindDefault(def, t)
+	if err != nil && t.Kind() != reflect.String && reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		out := reflect.New(t)
+		if textErr := out.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(def)); textErr != nil {
+			return reflect.Value{}, textErr
+		}
+		return out.Elem(), nil
 	}
-
-	return ptr.Elem(), nil
+	return v, err
 }
 
-func parseDefault(def string, t reflect.Type) (reflect.Value, error) {
+func parseKindDefault(def string, t reflect.Type) (reflect.Value, error) {
 	switch t.Kind() {
 	case reflect.String:
-		return reflect.ValueOf(def), nil
+		return reflect.ValueOf(def).Convert(t), nil
 
 	case reflect.Bool:
 		v, err := strconv.ParseBool(def)
-		return reflect.ValueOf(v), err
+		return reflect.ValueOf(v).Convert(t), err
 
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		v, err := strconv.ParseInt(def, 10, 64)
@@ -276,8 +634,8 @@ func parseDefault(def string, t reflect.Type) (reflect.Value, error) {
 		return reflect.ValueOf(v).Convert(t), err
 
 	case reflect.Slice:
-		if t.Elem().Kind() == reflect.String {
-			return reflect.ValueOf(strings.Split(def, ",")), nil
+		if v := reflect.ValueOf(strings.Split(def, ",")); v.Type().ConvertibleTo(t) {
+			return v.Convert(t), nil
 		}
 		return reflect.Value{}, fmt.Errorf("unsupported slice type")
 
@@ -298,16 +656,25 @@ func toSnakeCase(s string) string {
 }
 
 func (b *Builder) HasErrs() bool {
-	return len(b.errs) > 0 && b.errs != nil
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.errs) > 0
 }
 
+// Errs returns the errors of the last load, with secret values masked.
+// Errors in the builder setup, such as an unsupported source, are always
+// included.
 func (b *Builder) Errs() []error {
-	return b.errs
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.redactErrs(b.errs)
 }
 
+// Panic panics with a report of the errors of the last load, grouped by
+// source, if there are any. The panic value is an error wrapping them.
 func (b *Builder) Panic() {
-	if b.HasErrs() {
-		panic(b.Errs)
+	if errs := b.Errs(); len(errs) > 0 {
+		panic(&panicError{errs: errs})
 	}
 }
 
@@ -358,3 +725,26 @@ func mergeMaps(dst, src map[string]any) map[string]any {
 	}
 	return dst
 }
+
+func deepCopyMap(src map[string]any) map[string]any {
+	dst := make(map[string]any, len(src))
+	for k, v := range src {
+		dst[k] = deepCopyValue(v)
+	}
+	return dst
+}
+
+func deepCopyValue(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		return deepCopyMap(val)
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = deepCopyValue(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
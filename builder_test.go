@@ -1,8 +1,15 @@
 package ascanius
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -265,6 +272,19 @@ func TestBadFiles(t *testing.T) {
 	require.Equal(t, len(builder.Errs()), 3)
 }
 
+func TestSourceKeepsPathCase(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "Configs")
+	require.NoError(t, os.Mkdir(dir, 0o755))
+	path := filepath.Join(dir, "App.JSON")
+	require.NoError(t, os.WriteFile(path, []byte(`{"name": "billing"}`), 0o644))
+
+	var cfg struct {
+		Name string
+	}
+	require.NoError(t, New().Source(path, 1).Load(&cfg).Err())
+	require.Equal(t, "billing", cfg.Name)
+}
+
 func TestFlatConfiguration(t *testing.T) {
 	type ServerCfg struct {
 		Host string
@@ -381,3 +401,218 @@ func FuzzJsonLoader(f *testing.F) {
 		builder.Load(&cfg)
 	})
 }
+
+func TestConcurrentLoading(t *testing.T) {
+	// every source blocks until all of them have started, which only
+	// happens when they are loaded in parallel
+	var started sync.WaitGroup
+	started.Add(4)
+	release := make(chan struct{})
+	go func() {
+		started.Wait()
+		close(release)
+	}()
+
+	b := New()
+	for i := range 4 {
+		// the slowest source has the highest priority and must still win
+		b.AddSource(&stubSource{
+			name:     fmt.Sprintf("fragment-%d", i),
+			priority: i,
+			delay:    time.Duration(i) * 10 * time.Millisecond,
+			data:     map[string]any{"host": fmt.Sprintf("host-%d", i), fmt.Sprintf("key_%d", i): i},
+			release:  release,
+			started:  &started,
+		})
+	}
+
+	var cfg struct {
+		Host string
+		Key0 int `cfg:"key_0"`
+		Key3 int `cfg:"key_3"`
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	b.LoadContext(ctx, &cfg)
+
+	require.False(t, b.HasErrs(), b.Errs())
+	assert.Equal(t, "host-3", cfg.Host)
+	assert.Equal(t, 0, cfg.Key0)
+	assert.Equal(t, 3, cfg.Key3)
+}
+
+func TestConcurrentLoadingErrorOrder(t *testing.T) {
+	for range 10 {
+		b := New().
+			Concurrency(2).
+			Source("missing-b.json", 2).
+			Source("missing-a.json", 1)
+		b.Load(&struct{}{})
+
+		require.Len(t, b.Errs(), 2)
+		assert.Contains(t, b.Errs()[0].Error(), "missing-a.json")
+		assert.Contains(t, b.Errs()[1].Error(), "missing-b.json")
+	}
+}
+
+func TestReloadAndInvalidate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"host": "a"}`), 0o600))
+
+	var cfg struct{ Host string }
+	b := New().Source(path, 1).Load(&cfg)
+	require.False(t, b.HasErrs(), b.Errs())
+	assert.Equal(t, "a", cfg.Host)
+
+	// cached until invalidated
+	require.NoError(t, os.WriteFile(path, []byte(`{"host": "b"}`), 0o600))
+	b.Load(&cfg)
+	assert.Equal(t, "a", cfg.Host)
+
+	b.Invalidate(path).Load(&cfg)
+	assert.Equal(t, "b", cfg.Host)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"host": "c"}`), 0o600))
+	value, _ := b.Reload().Lookup("host")
+	assert.Equal(t, "c", value)
+}
+
+func TestErrsPerLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	b := New().Source("config.xml", 1).Source(path, 2)
+	require.Len(t, b.Errs(), 1)
+
+	for range 2 {
+		b.Load(&struct{}{})
+		require.Len(t, b.Errs(), 2)
+	}
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"host": "a"}`), 0o600))
+	b.Load(&struct{}{})
+	require.Len(t, b.Errs(), 1)
+	assert.Contains(t, b.Errs()[0].Error(), "unsupported source type for config.xml")
+}
+
+func TestClone(t *testing.T) {
+	base := New().
+		AddSource(&stubSource{name: "defaults", priority: 1, data: map[string]any{"host": "localhost", "port": 80}}).
+		SecretKeys("host")
+	derived := base.Clone().
+		AddSource(&stubSource{name: "overrides", priority: 2, data: map[string]any{"port": 8080}})
+
+	port, _ := base.Lookup("port")
+	assert.Equal(t, 80, port)
+	port, _ = derived.Lookup("port")
+	assert.Equal(t, 8080, port)
+	assert.True(t, derived.IsSecret("host"))
+	assert.Len(t, base.Explain("port"), 1)
+}
+
+func TestConcurrentUse(t *testing.T) {
+	b := New().
+		AddSource(&stubSource{name: "b", priority: 2, data: map[string]any{"port": 8080}}).
+		AddSource(&stubSource{name: "a", priority: 1, data: map[string]any{"host": "localhost", "port": 80}})
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var cfg struct {
+				Host string
+				Port int
+			}
+			for range 20 {
+				switch i % 4 {
+				case 0:
+					b.Load(&cfg)
+					assert.Equal(t, 8080, cfg.Port)
+				case 1:
+					b.Invalidate("a")
+				case 2:
+					_, _ = b.Lookup("host")
+					_ = b.Errs()
+				case 3:
+					_ = b.Clone().Reload().String()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// registration order is kept, sorting happens on a copy
+	assert.Equal(t, "b", b.sources[0].Name())
+	assert.False(t, b.HasErrs(), b.Errs())
+}
+
+// benchConfig is a large config struct, bound repeatedly by the benchmarks.
+type benchConfig struct {
+	Server  ServerConfig
+	Mongo   MongoConfig
+	Log     LogConfig
+	Workers []struct {
+		Name     string
+		Queues   []string
+		Capacity int
+		Timeout  time.Duration
+	}
+	Limits map[string]int
+}
+
+func benchData(b *testing.B) map[string]any {
+	workers := make([]any, 50)
+	for i := range workers {
+		workers[i] = map[string]any{
+			"name":     fmt.Sprintf("worker-%d", i),
+			"queues":   []any{"default", "mail", "reports"},
+			"capacity": float64(i * 10),
+			"timeout":  float64(time.Second),
+		}
+	}
+	limits := make(map[string]any, 50)
+	for i := range 50 {
+		limits[fmt.Sprintf("route_%d", i)] = float64(i)
+	}
+
+	var data map[string]any
+	raw, err := os.ReadFile("files/mongo.json")
+	require.NoError(b, err)
+	require.NoError(b, json.Unmarshal(raw, &data))
+	data = normalizeKeysToSnakeCase(data)
+	data["workers"] = workers
+	data["limits"] = limits
+	return data
+}
+
+func BenchmarkLoad(b *testing.B) {
+	builder := New().AddSource(&stubSource{name: "bench", priority: 1, data: benchData(b)})
+	b.ReportAllocs()
+	for b.Loop() {
+		var cfg benchConfig
+		builder.Load(&cfg)
+	}
+	require.False(b, builder.HasErrs(), builder.Errs())
+}
+
+func BenchmarkConvertValue(b *testing.B) {
+	data := benchData(b)
+	typ := reflect.TypeOf(benchConfig{}.Workers)
+
+	b.Run("direct", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			_, err := convertValue(data["workers"], typ)
+			require.NoError(b, err)
+		}
+	})
+
+	// the JSON round trip used before binding plans
+	b.Run("json", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			_, err := convertJson(data["workers"], typ)
+			require.NoError(b, err)
+		}
+	})
+}
@@ -0,0 +1,255 @@
+// Code generated by ascanius-gen. DO NOT EDIT.
+
+package example
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"github.com/adrenaissance/ascanius"
+)
+
+// BindAppConfig binds data to a new AppConfig the way Builder.Load does.
+func BindAppConfig(data map[string]any) (AppConfig, error) {
+	var cfg AppConfig
+	err := errors.Join(bindAppConfig(&cfg, data, "")...)
+	return cfg, err
+}
+
+func init() {
+	ascanius.RegisterBinder(func(cfg *AppConfig, data map[string]any, path string) error {
+		return errors.Join(bindAppConfig(cfg, data, path)...)
+	}, "mongo.uri", "mongo.password")
+}
+
+func bindAppConfig(cfg *AppConfig, data map[string]any, path string) []error {
+	if section, ok := data["app_config"].(map[string]any); ok {
+		return bindAppConfig(cfg, section, ascanius.JoinKey(path, "app_config"))
+	}
+
+	var errs []error
+
+	// Name
+	if v, ok := data["name"]; ok && v != nil {
+		if x, err := ascanius.ConvertString[string](v); err != nil {
+			errs = append(errs, ascanius.NewBindError[string](path, "name", err))
+		} else {
+			cfg.Name = x
+		}
+	}
+
+	// Level
+	if v, ok := data["level"]; !ok {
+		cfg.Level = LogLevel("info")
+	} else if v != nil {
+		if x, err := ascanius.ConvertString[LogLevel](v); err != nil {
+			errs = append(errs, ascanius.NewBindError[LogLevel](path, "level", err))
+		} else {
+			cfg.Level = x
+		}
+	}
+
+	// Ratio
+	if v, ok := data["ratio"]; !ok {
+		cfg.Ratio = float32(0.5)
+	} else if v != nil {
+		if x, err := ascanius.ConvertFloat[float32](v); err != nil {
+			errs = append(errs, ascanius.NewBindError[float32](path, "ratio", err))
+		} else {
+			cfg.Ratio = x
+		}
+	}
+
+	// Hosts
+	if v, ok := data["hosts"]; !ok {
+		cfg.Hosts = []string{"a", "b"}
+	} else if v != nil {
+		if x, err := ascanius.ConvertStringSlice(v); err != nil {
+			errs = append(errs, ascanius.NewBindError[[]string](path, "hosts", err))
+		} else {
+			cfg.Hosts = x
+		}
+	}
+
+	// Labels
+	if v, ok := data["labels"]; ok && v != nil {
+		if x, err := ascanius.Convert[map[string]string](v); err != nil {
+			errs = append(errs, ascanius.NewBindError[map[string]string](path, "labels", err))
+		} else {
+			cfg.Labels = x
+		}
+	}
+
+	// Server
+	if v, ok := data["server"]; ok && v != nil {
+		if m, ok := v.(map[string]any); ok {
+			errs = append(errs, bindServer(&cfg.Server, m, ascanius.JoinKey(path, "server"))...)
+		} else if x, err := ascanius.Convert[Server](v); err != nil {
+			errs = append(errs, ascanius.NewBindError[Server](path, "server", err))
+		} else {
+			cfg.Server = x
+		}
+	}
+
+	// Mongo
+	if v, ok := data["mongo"]; ok && v != nil {
+		if m, ok := v.(map[string]any); ok {
+			errs = append(errs, bindMongo(&cfg.Mongo, m, ascanius.JoinKey(path, "mongo"))...)
+		} else if x, err := ascanius.Convert[Mongo](v); err != nil {
+			errs = append(errs, ascanius.NewBindError[Mongo](path, "mongo", err))
+		} else {
+			cfg.Mongo = x
+		}
+	}
+
+	// Replicas
+	if v, ok := data["replicas"]; ok && v != nil {
+		if x, err := ascanius.Convert[[]Mongo](v); err != nil {
+			errs = append(errs, ascanius.NewBindError[[]Mongo](path, "replicas", err))
+		} else {
+			cfg.Replicas = x
+		}
+	}
+	return errs
+}
+
+func bindServer(cfg *Server, data map[string]any, path string) []error {
+	if section, ok := data["server"].(map[string]any); ok {
+		return bindServer(cfg, section, ascanius.JoinKey(path, "server"))
+	}
+
+	var errs []error
+
+	// Host
+	if v, ok := data["host"]; !ok {
+		cfg.Host = string("0.0.0.0")
+	} else if v != nil {
+		if x, err := ascanius.ConvertString[string](v); err != nil {
+			errs = append(errs, ascanius.NewBindError[string](path, "host", err))
+		} else {
+			cfg.Host = x
+		}
+	}
+
+	// Port
+	if v, ok := data["port"]; !ok {
+		cfg.Port = uint16(8080)
+	} else if v != nil {
+		if x, err := ascanius.ConvertUint[uint16](v); err != nil {
+			errs = append(errs, ascanius.NewBindError[uint16](path, "port", err))
+		} else {
+			cfg.Port = x
+		}
+	}
+
+	// Timeout
+	if v, ok := data["timeout"]; !ok {
+		cfg.Timeout = time.Duration(5000000000)
+	} else if v != nil {
+		if x, err := ascanius.ConvertDuration(v); err != nil {
+			errs = append(errs, ascanius.NewBindError[time.Duration](path, "timeout", err))
+		} else {
+			cfg.Timeout = x
+		}
+	}
+
+	// Allowed
+	if v, ok := data["allowed"]; ok && v != nil {
+		if x, err := ascanius.Convert[[]net.IP](v); err != nil {
+			errs = append(errs, ascanius.NewBindError[[]net.IP](path, "allowed", err))
+		} else {
+			cfg.Allowed = x
+		}
+	}
+
+	// Tls
+	if v, ok := data["tls"]; ok && v != nil {
+		if m, ok := v.(map[string]any); ok {
+			errs = append(errs, bindTls(&cfg.Tls, m, ascanius.JoinKey(path, "tls"))...)
+		} else if x, err := ascanius.Convert[Tls](v); err != nil {
+			errs = append(errs, ascanius.NewBindError[Tls](path, "tls", err))
+		} else {
+			cfg.Tls = x
+		}
+	}
+	return errs
+}
+
+func bindMongo(cfg *Mongo, data map[string]any, path string) []error {
+	if section, ok := data["mongo"].(map[string]any); ok {
+		return bindMongo(cfg, section, ascanius.JoinKey(path, "mongo"))
+	}
+
+	var errs []error
+
+	// Uri
+	if v, ok := data["uri"]; ok && v != nil {
+		if x, err := ascanius.ConvertString[string](v); err != nil {
+			errs = append(errs, ascanius.NewBindError[string](path, "uri", err))
+		} else {
+			cfg.Uri = ascanius.NewSecret[string](x)
+		}
+	}
+
+	// Password
+	if v, ok := data["password"]; ok && v != nil {
+		if x, err := ascanius.ConvertString[string](v); err != nil {
+			errs = append(errs, ascanius.NewBindError[string](path, "password", err))
+		} else {
+			cfg.Password = x
+		}
+	}
+
+	// ReplicaSet
+	if v, ok := data["replica_set"]; ok && v != nil {
+		if x, err := ascanius.ConvertString[string](v); err != nil {
+			errs = append(errs, ascanius.NewBindError[string](path, "replica_set", err))
+		} else {
+			cfg.ReplicaSet = x
+		}
+	}
+
+	// PoolSize
+	if v, ok := data["pool_size"]; !ok {
+		cfg.PoolSize = int8(10)
+	} else if v != nil {
+		if x, err := ascanius.ConvertInt[int8](v); err != nil {
+			errs = append(errs, ascanius.NewBindError[int8](path, "pool_size", err))
+		} else {
+			cfg.PoolSize = x
+		}
+	}
+	return errs
+}
+
+func bindTls(cfg *Tls, data map[string]any, path string) []error {
+	if section, ok := data["tls"].(map[string]any); ok {
+		return bindTls(cfg, section, ascanius.JoinKey(path, "tls"))
+	}
+
+	var errs []error
+
+	// Enabled
+	if v, ok := data["enabled"]; !ok {
+		cfg.Enabled = bool(true)
+	} else if v != nil {
+		if x, err := ascanius.ConvertBool[bool](v); err != nil {
+			errs = append(errs, ascanius.NewBindError[bool](path, "enabled", err))
+		} else {
+			cfg.Enabled = x
+		}
+	}
+
+	// Cert
+	if v, ok := data["cert"]; !ok {
+		cfg.Cert = string("/etc/ssl/server.crt")
+	} else if v != nil {
+		if x, err := ascanius.ConvertString[string](v); err != nil {
+			errs = append(errs, ascanius.NewBindError[string](path, "cert", err))
+		} else {
+			cfg.Cert = x
+		}
+	}
+	return errs
+}
@@ -0,0 +1,45 @@
+// Package example holds the config structs used to test ascanius-gen.
+package example
+
+import (
+	"net"
+	"time"
+
+	"github.com/adrenaissance/ascanius"
+)
+
+//go:generate go run ../.. -type AppConfig
+
+type LogLevel string
+
+type Tls struct {
+	Enabled bool   `def:"true"`
+	Cert    string `def:"/etc/ssl/server.crt"`
+}
+
+type Server struct {
+	Host    string        `def:"0.0.0.0"`
+	Port    uint16        `def:"8080"`
+	Timeout time.Duration `def:"5s"`
+	Allowed []net.IP
+	Tls     Tls
+}
+
+type Mongo struct {
+	Uri        ascanius.Secret[string] `cfg:"uri"`
+	Password   string                  `secret:"true"`
+	ReplicaSet string
+	PoolSize   int8 `def:"10"`
+}
+
+type AppConfig struct {
+	Name     string
+	Level    LogLevel `def:"info"`
+	Ratio    float32  `def:"0.5"`
+	Hosts    []string `def:"a,b"`
+	Labels   map[string]string
+	Server   Server
+	Mongo    Mongo
+	Replicas []Mongo
+	internal string
+}
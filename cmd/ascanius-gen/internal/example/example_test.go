@@ -0,0 +1,85 @@
+package example
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/adrenaissance/ascanius"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reflected has the fields of AppConfig but no generated binder.
+type reflected AppConfig
+
+const document = `{
+	"name": "api",
+	"level": "debug",
+	"labels": {"team": "core"},
+	"server": {
+		"port": 9090,
+		"timeout": "30s",
+		"allowed": ["10.0.0.1", "10.0.0.2"],
+		"tls": {"enabled": false}
+	},
+	"mongo": {"uri": "mongodb://db", "password": "hunter2", "replica_set": "rs0"},
+	"replicas": [{"uri": "mongodb://replica", "pool_size": 3}]
+}`
+
+func load(t *testing.T, content string, target any) *ascanius.Builder {
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return ascanius.New().Source(path, 1).Load(target)
+}
+
+func TestGeneratedMatchesReflection(t *testing.T) {
+	var data map[string]any
+	require.NoError(t, json.Unmarshal([]byte(document), &data))
+
+	generated, err := BindAppConfig(data)
+	require.NoError(t, err)
+
+	var want reflected
+	b := load(t, document, &want)
+	require.False(t, b.HasErrs(), b.Errs())
+
+	assert.Equal(t, AppConfig(want), generated)
+	assert.Equal(t, LogLevel("debug"), generated.Level)
+	assert.Equal(t, []string{"a", "b"}, generated.Hosts)
+	assert.Equal(t, 30*time.Second, generated.Server.Timeout)
+	assert.Equal(t, []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")}, generated.Server.Allowed)
+	assert.Equal(t, "/etc/ssl/server.crt", generated.Server.Tls.Cert)
+	assert.Equal(t, "mongodb://db", generated.Mongo.Uri.Value())
+	assert.Equal(t, int8(3), generated.Replicas[0].PoolSize)
+}
+
+func TestGeneratedBindErrors(t *testing.T) {
+	data := map[string]any{
+		"ratio":  "half",
+		"server": map[string]any{"port": 70000},
+		"mongo":  map[string]any{"pool_size": 1000},
+	}
+	_, err := BindAppConfig(data)
+
+	var bindErr *ascanius.BindError
+	require.ErrorAs(t, err, &bindErr)
+	assert.Equal(t, "ratio", bindErr.Key)
+	assert.Contains(t, err.Error(), "cannot bind server.port to uint16")
+	assert.Contains(t, err.Error(), "cannot bind mongo.pool_size to int8")
+}
+
+func TestBuilderUsesGeneratedBinder(t *testing.T) {
+	var cfg AppConfig
+	b := load(t, document, &cfg)
+	require.False(t, b.HasErrs(), b.Errs())
+
+	assert.Equal(t, uint16(9090), cfg.Server.Port)
+	assert.Equal(t, "rs0", cfg.Mongo.ReplicaSet)
+	assert.True(t, b.IsSecret("mongo.uri"))
+	assert.True(t, b.IsSecret("mongo.password"))
+	assert.NotContains(t, b.String(), "hunter2")
+}
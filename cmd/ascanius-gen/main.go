@@ -0,0 +1,596 @@
+// Command ascanius-gen writes reflection-free binders for config structs.
+// For every type named with -type it emits Bind<Type>(map[string]any)
+// (<Type>, error) and registers the binder with the builder, which then
+// uses it instead of reflection whenever it loads into that type:
+//
+//	//go:generate go run github.com/adrenaissance/ascanius/cmd/ascanius-gen -type AppConfig
+//
+// Generated binders follow the rules of Builder.Load: cfg tags or snake_case
+// keys, def defaults, nested structs and the same conversions. Types other
+// than strings, booleans, numbers, time.Duration, []string and structs of the
+// same package are converted with ascanius.Convert, which uses reflection.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adrenaissance/ascanius"
+)
+
+const (
+	ASCANIUS_IMPORT = "github.com/adrenaissance/ascanius"
+	DEFAULT_OUTPUT  = "ascanius_binders.go"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stderr))
+}
+
+func run(args []string, stderr io.Writer) int {
+	flags := flag.NewFlagSet("ascanius-gen", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	types := flags.String("type", "", "comma-separated list of config struct types")
+	dir := flags.String("dir", ".", "package directory")
+	output := flags.String("output", DEFAULT_OUTPUT, "output file, relative to -dir")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *types == "" {
+		fmt.Fprintln(stderr, "ascanius-gen: -type is required")
+		return 2
+	}
+
+	src, err := generate(*dir, *output, strings.Split(*types, ","))
+	if err == nil {
+		err = os.WriteFile(filepath.Join(*dir, *output), src, 0o644)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "ascanius-gen:", err)
+		return 1
+	}
+	return 0
+}
+
+// kind is how a field value is converted.
+type kind int
+
+const (
+	kindOther kind = iota
+	kindString
+	kindBool
+	kindInt
+	kindUint
+	kindFloat
+	kindDuration
+	kindStringSlice
+	kindStruct
+)
+
+type generator struct {
+	fset    *token.FileSet
+	pkg     string
+	structs map[string]*ast.StructType
+	named   map[string]ast.Expr
+	methods map[string]map[string]bool
+	// imports maps the names used in the package to import paths
+	imports map[string]string
+	// used collects the imports needed by the generated code
+	used map[string]string
+
+	buf    bytes.Buffer
+	done   map[string]bool
+	queue  []string
+	errs   []error
+	parent string
+}
+
+// generate parses the package in dir, skipping output, and returns the
+// formatted source of the binders for types.
+func generate(dir, output string, types []string) ([]byte, error) {
+	g := &generator{
+		fset:    token.NewFileSet(),
+		structs: make(map[string]*ast.StructType),
+		named:   make(map[string]ast.Expr),
+		methods: make(map[string]map[string]bool),
+		imports: make(map[string]string),
+		used:    map[string]string{"errors": "errors", "ascanius": ASCANIUS_IMPORT},
+		done:    make(map[string]bool),
+	}
+	if err := g.parse(dir, output); err != nil {
+		return nil, err
+	}
+
+	var roots bytes.Buffer
+	for _, name := range types {
+		name = strings.TrimSpace(name)
+		if _, ok := g.structs[name]; !ok {
+			return nil, fmt.Errorf("struct type %s not found in %s", name, dir)
+		}
+		g.root(&roots, name)
+	}
+	for len(g.queue) > 0 {
+		name := g.queue[0]
+		g.queue = g.queue[1:]
+		g.bindFunc(name)
+	}
+	if len(g.errs) > 0 {
+		return nil, errors.Join(g.errs...)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by ascanius-gen. DO NOT EDIT.\n\npackage %s\n\nimport (\n", g.pkg)
+	names := make([]string, 0, len(g.used))
+	for name := range g.used {
+		names = append(names, name)
+	}
+	// standard library first, like goimports
+	std := func(path string) bool { return !strings.Contains(strings.Split(path, "/")[0], ".") }
+	sort.Slice(names, func(i, j int) bool {
+		a, b := g.used[names[i]], g.used[names[j]]
+		if std(a) != std(b) {
+			return std(a)
+		}
+		return a < b
+	})
+	for i, name := range names {
+		path := g.used[name]
+		if i > 0 && std(g.used[names[i-1]]) && !std(path) {
+			out.WriteString("\n")
+		}
+		if filepath.Base(path) == name {
+			fmt.Fprintf(&out, "\t%q\n", path)
+		} else {
+			fmt.Fprintf(&out, "\t%s %q\n", name, path)
+		}
+	}
+	out.WriteString(")\n")
+	out.Write(roots.Bytes())
+	out.Write(g.buf.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w\n%s", err, out.Bytes())
+	}
+	return src, nil
+}
+
+func (g *generator) parse(dir, output string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") || filepath.Base(path) == filepath.Base(output) {
+			continue
+		}
+		file, err := parser.ParseFile(g.fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return err
+		}
+		g.pkg = file.Name.Name
+
+		for _, imp := range file.Imports {
+			path, _ := strconv.Unquote(imp.Path.Value)
+			name := filepath.Base(path)
+			if imp.Name != nil {
+				name = imp.Name.Name
+			}
+			g.imports[name] = path
+		}
+
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					ts, ok := spec.(*ast.TypeSpec)
+					if !ok || ts.TypeParams != nil {
+						continue
+					}
+					if st, ok := ts.Type.(*ast.StructType); ok {
+						g.structs[ts.Name.Name] = st
+					} else {
+						g.named[ts.Name.Name] = ts.Type
+					}
+				}
+			case *ast.FuncDecl:
+				if decl.Recv == nil || len(decl.Recv.List) == 0 {
+					continue
+				}
+				recv := decl.Recv.List[0].Type
+				if star, ok := recv.(*ast.StarExpr); ok {
+					recv = star.X
+				}
+				if ident, ok := recv.(*ast.Ident); ok {
+					if g.methods[ident.Name] == nil {
+						g.methods[ident.Name] = make(map[string]bool)
+					}
+					g.methods[ident.Name][decl.Name.Name] = true
+				}
+			}
+		}
+	}
+	if g.pkg == "" {
+		return fmt.Errorf("no Go files in %s", dir)
+	}
+	return nil
+}
+
+// root writes the exported Bind function of name and the init function
+// registering it.
+func (g *generator) root(w *bytes.Buffer, name string) {
+	g.enqueue(name)
+
+	var secrets []string
+	g.secretKeys(&secrets, name, "")
+	quoted := make([]string, len(secrets))
+	for i, key := range secrets {
+		quoted[i] = strconv.Quote(key)
+	}
+	registration := ""
+	if len(quoted) > 0 {
+		registration = ", " + strings.Join(quoted, ", ")
+	}
+
+	fmt.Fprintf(w, `
+// Bind%[1]s binds data to a new %[1]s the way Builder.Load does.
+func Bind%[1]s(data map[string]any) (%[1]s, error) {
+	var cfg %[1]s
+	err := errors.Join(%[2]s(&cfg, data, "")...)
+	return cfg, err
+}
+
+func init() {
+	ascanius.RegisterBinder(func(cfg *%[1]s, data map[string]any, path string) error {
+		return errors.Join(%[2]s(cfg, data, path)...)
+	}%[3]s)
+}
+`, name, bindName(name), registration)
+}
+
+func bindName(typeName string) string {
+	return "bind" + strings.ToUpper(typeName[:1]) + typeName[1:]
+}
+
+func (g *generator) enqueue(name string) {
+	if !g.done[name] {
+		g.done[name] = true
+		g.queue = append(g.queue, name)
+	}
+}
+
+// field is an exported struct field as Load sees it.
+type field struct {
+	name   string
+	key    string
+	typ    ast.Expr
+	tag    reflect.StructTag
+	secret bool
+	// inner is the wrapped type of an ascanius.Secret field
+	inner ast.Expr
+}
+
+func (g *generator) fields(st *ast.StructType) []field {
+	var out []field
+	for _, f := range st.Fields.List {
+		var tag reflect.StructTag
+		if f.Tag != nil {
+			raw, _ := strconv.Unquote(f.Tag.Value)
+			tag = reflect.StructTag(raw)
+		}
+
+		names := make([]string, 0, len(f.Names))
+		for _, n := range f.Names {
+			names = append(names, n.Name)
+		}
+		if len(names) == 0 {
+			// embedded fields are named after their type
+			names = append(names, embeddedName(f.Type))
+		}
+
+		for _, name := range names {
+			if !ast.IsExported(name) {
+				continue
+			}
+			fd := field{name: name, key: tag.Get("cfg"), typ: f.Type, tag: tag, secret: tag.Get("secret") == "true"}
+			if fd.key == "" {
+				fd.key = ascanius.SnakeCase(name)
+			}
+			if inner, ok := g.secretInner(f.Type); ok {
+				fd.secret, fd.inner = true, inner
+			}
+			out = append(out, fd)
+		}
+	}
+	return out
+}
+
+func embeddedName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.StarExpr:
+		return embeddedName(t.X)
+	case *ast.IndexExpr:
+		return embeddedName(t.X)
+	default:
+		return ""
+	}
+}
+
+func (g *generator) secretInner(expr ast.Expr) (ast.Expr, bool) {
+	idx, ok := expr.(*ast.IndexExpr)
+	if !ok {
+		return nil, false
+	}
+	sel, ok := idx.X.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Secret" {
+		return nil, false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok || g.imports[pkg.Name] != ASCANIUS_IMPORT {
+		return nil, false
+	}
+	return idx.Index, true
+}
+
+// secretKeys collects the key paths of secret fields below the struct name.
+func (g *generator) secretKeys(dst *[]string, name, path string) {
+	for _, f := range g.fields(g.structs[name]) {
+		key := ascanius.JoinKey(path, f.key)
+		if f.secret {
+			*dst = append(*dst, key)
+			continue
+		}
+		if ident, ok := f.typ.(*ast.Ident); ok && g.kindOf(f.typ) == kindStruct {
+			g.secretKeys(dst, ident.Name, key)
+		}
+	}
+}
+
+func (g *generator) kindOf(expr ast.Expr) kind {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		switch t.Name {
+		case "string":
+			return kindString
+		case "bool":
+			return kindBool
+		case "int", "int8", "int16", "int32", "int64", "rune":
+			return kindInt
+		case "uint", "uint8", "uint16", "uint32", "uint64", "byte":
+			return kindUint
+		case "float32", "float64":
+			return kindFloat
+		}
+		// types decoding themselves go through Convert like they go
+		// through convertValue in Load
+		if m := g.methods[t.Name]; m["UnmarshalText"] || m["UnmarshalJSON"] {
+			return kindOther
+		}
+		if _, ok := g.structs[t.Name]; ok {
+			return kindStruct
+		}
+		if underlying, ok := g.named[t.Name]; ok {
+			if k := g.kindOf(underlying); k != kindStruct && k != kindStringSlice && k != kindDuration {
+				return k
+			}
+		}
+
+	case *ast.SelectorExpr:
+		if pkg, ok := t.X.(*ast.Ident); ok && g.imports[pkg.Name] == "time" && t.Sel.Name == "Duration" {
+			return kindDuration
+		}
+
+	case *ast.ArrayType:
+		if elem, ok := t.Elt.(*ast.Ident); ok && t.Len == nil && elem.Name == "string" {
+			return kindStringSlice
+		}
+	}
+	return kindOther
+}
+
+// typeString prints expr and records the imports it needs.
+func (g *generator) typeString(expr ast.Expr) string {
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if pkg, ok := sel.X.(*ast.Ident); ok {
+				if path, ok := g.imports[pkg.Name]; ok {
+					g.used[pkg.Name] = path
+				}
+			}
+		}
+		return true
+	})
+	var buf bytes.Buffer
+	printer.Fprint(&buf, g.fset, expr)
+	return buf.String()
+}
+
+// bindFunc writes the function binding the struct name.
+func (g *generator) bindFunc(name string) {
+	section := ascanius.SnakeCase(name)
+	fmt.Fprintf(&g.buf, `
+func %[1]s(cfg *%[2]s, data map[string]any, path string) []error {
+	if section, ok := data[%[3]q].(map[string]any); ok {
+		return %[1]s(cfg, section, ascanius.JoinKey(path, %[3]q))
+	}
+
+	var errs []error
+`, bindName(name), name, section)
+
+	for _, f := range g.fields(g.structs[name]) {
+		g.parent = name
+		g.bindField(f)
+	}
+
+	g.buf.WriteString("\treturn errs\n}\n")
+}
+
+func (g *generator) bindField(f field) {
+	typ := f.typ
+	if f.inner != nil {
+		typ = f.inner
+	}
+	k := g.kindOf(typ)
+	typeName := g.typeString(typ)
+	assign := "x"
+	if f.inner != nil {
+		assign = fmt.Sprintf("ascanius.NewSecret[%s](x)", typeName)
+	}
+
+	def, hasDef := "", false
+	if raw := f.tag.Get("def"); raw != "" {
+		var err error
+		def, hasDef, err = g.defaultExpr(k, typeName, raw)
+		if err != nil {
+			g.errs = append(g.errs, fmt.Errorf("%s.%s: %w", g.parent, f.name, err))
+		}
+		if hasDef && f.inner != nil {
+			def = fmt.Sprintf("ascanius.NewSecret[%s](%s)", typeName, def)
+		}
+	}
+
+	fmt.Fprintf(&g.buf, "\n\t// %s\n", f.name)
+	if hasDef {
+		fmt.Fprintf(&g.buf, "\tif v, ok := data[%q]; !ok {\n", f.key)
+		if k == kindOther {
+			// only known at run time whether the type has a default syntax
+			fmt.Fprintf(&g.buf, "\t\tif d, ok := ascanius.ParseDefault[%s](%q); ok {\n\t\t\tcfg.%s = %s\n\t\t}\n", typeName, f.tag.Get("def"), f.name, def)
+		} else {
+			fmt.Fprintf(&g.buf, "\t\tcfg.%s = %s\n", f.name, def)
+		}
+		g.buf.WriteString("\t} else if v != nil {\n")
+	} else {
+		fmt.Fprintf(&g.buf, "\tif v, ok := data[%q]; ok && v != nil {\n", f.key)
+	}
+
+	if k == kindStruct && f.inner == nil {
+		fmt.Fprintf(&g.buf, "\t\tif m, ok := v.(map[string]any); ok {\n\t\t\terrs = append(errs, %s(&cfg.%s, m, ascanius.JoinKey(path, %q))...)\n\t\t} else ",
+			bindName(typeName), f.name, f.key)
+		g.enqueue(typeName)
+	} else {
+		g.buf.WriteString("\t\t")
+	}
+
+	fmt.Fprintf(&g.buf, `if x, err := %s; err != nil {
+			errs = append(errs, ascanius.NewBindError[%s](path, %q, err))
+		} else {
+			cfg.%s = %s
+		}
+	}
+`, g.convertCall(k, typeName), typeName, f.key, f.name, assign)
+}
+
+func (g *generator) convertCall(k kind, typeName string) string {
+	switch k {
+	case kindString:
+		return fmt.Sprintf("ascanius.ConvertString[%s](v)", typeName)
+	case kindBool:
+		return fmt.Sprintf("ascanius.ConvertBool[%s](v)", typeName)
+	case kindInt:
+		return fmt.Sprintf("ascanius.ConvertInt[%s](v)", typeName)
+	case kindUint:
+		return fmt.Sprintf("ascanius.ConvertUint[%s](v)", typeName)
+	case kindFloat:
+		return fmt.Sprintf("ascanius.ConvertFloat[%s](v)", typeName)
+	case kindDuration:
+		return "ascanius.ConvertDuration(v)"
+	case kindStringSlice:
+		return "ascanius.ConvertStringSlice(v)"
+	default:
+		return fmt.Sprintf("ascanius.Convert[%s](v)", typeName)
+	}
+}
+
+// defaultExpr returns the Go expression of a def tag, following the rules
+// of Load: invalid defaults and kinds without a default syntax are ignored.
+// Defaults that do not fit the field type are reported.
+func (g *generator) defaultExpr(k kind, typeName, def string) (string, bool, error) {
+	switch k {
+	case kindString:
+		return fmt.Sprintf("%s(%q)", typeName, def), true, nil
+
+	case kindBool:
+		v, err := strconv.ParseBool(def)
+		if err != nil {
+			return "", false, nil
+		}
+		return fmt.Sprintf("%s(%t)", typeName, v), true, nil
+
+	case kindInt, kindDuration:
+		if d, err := time.ParseDuration(def); err == nil && k == kindDuration {
+			return fmt.Sprintf("%s(%d)", typeName, int64(d)), true, nil
+		}
+		v, err := strconv.ParseInt(def, 10, 64)
+		if err != nil {
+			return "", false, nil
+		}
+		if bits := intBits(typeName); bits < 64 && (v < -(1<<(bits-1)) || v >= 1<<(bits-1)) {
+			return "", false, fmt.Errorf("default %s overflows %s", def, typeName)
+		}
+		return fmt.Sprintf("%s(%d)", typeName, v), true, nil
+
+	case kindUint:
+		v, err := strconv.ParseUint(def, 10, 64)
+		if err != nil {
+			return "", false, nil
+		}
+		if bits := intBits(typeName); bits < 64 && v >= 1<<bits {
+			return "", false, fmt.Errorf("default %s overflows %s", def, typeName)
+		}
+		return fmt.Sprintf("%s(%d)", typeName, v), true, nil
+
+	case kindFloat:
+		v, err := strconv.ParseFloat(def, 64)
+		if err != nil {
+			return "", false, nil
+		}
+		return fmt.Sprintf("%s(%s)", typeName, strconv.FormatFloat(v, 'g', -1, 64)), true, nil
+
+	case kindStringSlice:
+		parts := strings.Split(def, ",")
+		for i, p := range parts {
+			parts[i] = strconv.Quote(p)
+		}
+		return fmt.Sprintf("[]string{%s}", strings.Join(parts, ", ")), true, nil
+
+	case kindOther:
+		return "d", true, nil
+
+	default:
+		return "", false, nil
+	}
+}
+
+// intBits returns the size of builtin integer types, 64 for anything else
+// which is range checked by the compiler instead.
+func intBits(typeName string) int {
+	switch strings.TrimPrefix(typeName, "u") {
+	case "int8", "byte":
+		return 8
+	case "int16":
+		return 16
+	case "int32", "rune":
+		return 32
+	default:
+		return 64
+	}
+}
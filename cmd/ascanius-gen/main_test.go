@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratedFileUpToDate(t *testing.T) {
+	dir := filepath.Join("internal", "example")
+	want, err := os.ReadFile(filepath.Join(dir, DEFAULT_OUTPUT))
+	require.NoError(t, err)
+
+	got, err := generate(dir, DEFAULT_OUTPUT, []string{"AppConfig"})
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(got), "run go generate ./cmd/ascanius-gen/...")
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.go"), []byte(`package config
+
+type Config struct {
+	Port uint16 `+"`def:\"8080\"`"+`
+}
+`), 0o600))
+
+	var stderr bytes.Buffer
+	require.Equal(t, 0, run([]string{"-dir", dir, "-type", "Config"}, &stderr), stderr.String())
+
+	src, err := os.ReadFile(filepath.Join(dir, DEFAULT_OUTPUT))
+	require.NoError(t, err)
+	assert.Contains(t, string(src), "func BindConfig(data map[string]any) (Config, error) {")
+	assert.Contains(t, string(src), "cfg.Port = uint16(8080)")
+}
+
+func TestRunErrors(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.go"), []byte(`package config
+
+type Config struct {
+	Retries uint8 `+"`def:\"300\"`"+`
+}
+`), 0o600))
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+		msg  string
+	}{
+		{"missing type", []string{"-dir", dir}, 2, "-type is required"},
+		{"unknown type", []string{"-dir", dir, "-type", "Other"}, 1, "struct type Other not found"},
+		{"default overflow", []string{"-dir", dir, "-type", "Config"}, 1, "Config.Retries: default 300 overflows uint8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+			assert.Equal(t, tt.code, run(tt.args, &stderr))
+			assert.Contains(t, stderr.String(), tt.msg)
+		})
+	}
+}
@@ -0,0 +1,554 @@
+// Command ascanius inspects, validates and converts configuration files
+// using the same sources and normalization as the ascanius library.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/adrenaissance/ascanius"
+	"github.com/adrenaissance/ascanius/internal/atomicfile"
+	"gopkg.in/yaml.v3"
+)
+
+const usage = `usage: ascanius <command> [flags] [args]
+
+commands:
+  merge   [flags] <source>...        merge sources and print the result
+  get     [flags] <key> <source>...  print the merged value of key
+  explain [flags] <key> <source>...  show which sources define key
+  validate [flags] <file>...         check that files parse
+  convert [flags] <file>             convert a file to another format
+  diff    -from <source>... -to <source>...
+                                     show what changes between two layerings
+  encrypt [key flags] [value|-]      encrypt a value, read from stdin by default
+  decrypt [key flags] [value|-]      decrypt a value, read from stdin by default
+  rotate  [key flags] [new key flags] <file>...
+                                     re-encrypt every value in files with a new key
+  migrate -steps <file> <file>...    upgrade files to the latest config version
+
+Sources are layered in the order given, later ones override earlier ones.
+Use "env" for the process environment.
+Run "ascanius <command> -h" for the flags of a command.
+`
+
+// stdin is read by encrypt and decrypt, replaced in tests.
+var stdin io.Reader = os.Stdin
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	cmd, args := args[0], args[1:]
+	var err error
+	switch cmd {
+	case "merge":
+		err = runMerge(args, stdout, stderr)
+	case "get":
+		err = runGet(args, stdout, stderr)
+	case "explain":
+		err = runExplain(args, stdout, stderr)
+	case "validate":
+		err = runValidate(args, stdout, stderr)
+	case "convert":
+		err = runConvert(args, stdout, stderr)
+	case "diff":
+		err = runDiff(args, stdout, stderr)
+	case "encrypt":
+		err = runEncrypt(args, stdout, stderr)
+	case "decrypt":
+		err = runDecrypt(args, stdout, stderr)
+	case "rotate":
+		err = runRotate(args, stdout, stderr)
+	case "migrate":
+		err = runMigrate(args, stdout, stderr)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", cmd, usage)
+		return 2
+	}
+
+	if err != nil {
+		if err != flag.ErrHelp {
+			fmt.Fprintln(stderr, "ascanius:", err)
+		}
+		return 1
+	}
+	return 0
+}
+
+type options struct {
+	flags  *flag.FlagSet
+	prefix string
+	sep    string
+	format string
+	reveal bool
+	keys   *keyOptions
+	// keepRefs leaves secret references unresolved
+	keepRefs bool
+}
+
+func newOptions(name string, stderr io.Writer, defaultFormat string) *options {
+	o := &options{flags: flag.NewFlagSet(name, flag.ContinueOnError)}
+	o.flags.SetOutput(stderr)
+	o.flags.StringVar(&o.prefix, "prefix", ascanius.DEFAULT_ENV_PREFIX, "environment variable prefix")
+	o.flags.StringVar(&o.sep, "sep", ascanius.DEFAULT_ENV_SEPARATOR, "environment variable separator")
+	if defaultFormat != "" {
+		o.flags.StringVar(&o.format, "o", defaultFormat, "output format: json, yaml, toml or env")
+	}
+	return o
+}
+
+type keyOptions struct {
+	file     string
+	env      string
+	identity string
+}
+
+func addKeyFlags(fs *flag.FlagSet, prefix, what string) *keyOptions {
+	k := &keyOptions{}
+	fs.StringVar(&k.file, prefix+"key-file", "", "AES-256-GCM key file "+what)
+	fs.StringVar(&k.env, prefix+"key-env", "", "environment variable holding the AES-256-GCM key "+what)
+	fs.StringVar(&k.identity, prefix+"age-identity", "", "age X25519 identity file "+what)
+	return k
+}
+
+func (k *keyOptions) providers() []ascanius.KeyProvider {
+	var providers []ascanius.KeyProvider
+	if k.file != "" {
+		providers = append(providers, ascanius.NewKeyFileProvider(k.file))
+	}
+	if k.env != "" {
+		providers = append(providers, ascanius.NewEnvKeyProvider(k.env))
+	}
+	if k.identity != "" {
+		providers = append(providers, ascanius.NewAgeIdentityProvider(k.identity))
+	}
+	return providers
+}
+
+func (k *keyOptions) provider() (ascanius.KeyProvider, error) {
+	providers := k.providers()
+	if len(providers) != 1 {
+		return nil, fmt.Errorf("exactly one key must be given")
+	}
+	return providers[0], nil
+}
+
+func (o *options) builder(sources []string) *ascanius.Builder {
+	b := ascanius.New().
+		EnvPrefix(o.prefix).
+		EnvSeparator(o.sep)
+	if o.reveal {
+		b.RevealSecrets()
+	}
+	if o.keepRefs {
+		b.KeepReferences()
+	}
+	if o.keys != nil {
+		b.Decrypt(o.keys.providers()...)
+	}
+	for i, src := range sources {
+		b.Source(src, i+1)
+	}
+	return b
+}
+
+func loadErrors(b *ascanius.Builder, stderr io.Writer) error {
+	if !b.HasErrs() {
+		return nil
+	}
+	for _, err := range b.Errs() {
+		fmt.Fprintln(stderr, err)
+	}
+	return fmt.Errorf("%d error(s) while loading configuration", len(b.Errs()))
+}
+
+func runMerge(args []string, stdout, stderr io.Writer) error {
+	o := newOptions("merge", stderr, "json")
+	o.keys = addKeyFlags(o.flags, "", "to decrypt values with")
+	o.flags.BoolVar(&o.reveal, "reveal", false, "print secret values instead of masking them")
+	o.keepRefs = true
+	if err := o.flags.Parse(args); err != nil {
+		return err
+	}
+	if o.flags.NArg() == 0 {
+		return fmt.Errorf("merge: at least one source is required")
+	}
+
+	b := o.builder(o.flags.Args())
+	b.Merged()
+	if err := loadErrors(b, stderr); err != nil {
+		return err
+	}
+	return b.Export(stdout, o.format)
+}
+
+func runGet(args []string, stdout, stderr io.Writer) error {
+	o := newOptions("get", stderr, "")
+	o.keys = addKeyFlags(o.flags, "", "to decrypt values with")
+	if err := o.flags.Parse(args); err != nil {
+		return err
+	}
+	if o.flags.NArg() < 2 {
+		return fmt.Errorf("get: usage: get <key> <source>...")
+	}
+
+	key := o.flags.Arg(0)
+	b := o.builder(o.flags.Args()[1:])
+	b.Merged()
+	if err := loadErrors(b, stderr); err != nil {
+		return err
+	}
+
+	value, ok := b.Redacted(nil).Lookup(key)
+	if !ok {
+		return fmt.Errorf("key %s not found", key)
+	}
+	return printValue(stdout, value)
+}
+
+func runExplain(args []string, stdout, stderr io.Writer) error {
+	o := newOptions("explain", stderr, "")
+	o.keys = addKeyFlags(o.flags, "", "to decrypt values with")
+	if err := o.flags.Parse(args); err != nil {
+		return err
+	}
+	if o.flags.NArg() < 2 {
+		return fmt.Errorf("explain: usage: explain <key> <source>...")
+	}
+
+	key := o.flags.Arg(0)
+	b := o.builder(o.flags.Args()[1:])
+	origins := b.Explain(key)
+	if err := loadErrors(b, stderr); err != nil {
+		return err
+	}
+	if len(origins) == 0 {
+		return fmt.Errorf("key %s is not set by any source", key)
+	}
+
+	fmt.Fprintf(stdout, "%s:\n", key)
+	for _, origin := range origins {
+		raw, err := json.Marshal(origin.Value)
+		if err != nil {
+			return err
+		}
+		marker := " "
+		if origin.Effective {
+			marker = "*"
+		}
+		fmt.Fprintf(stdout, "%s [%d] %s = %s\n", marker, origin.Priority, origin.Source, raw)
+	}
+	return nil
+}
+
+func runValidate(args []string, stdout, stderr io.Writer) error {
+	o := newOptions("validate", stderr, "")
+	schemaPath := o.flags.String("schema", "", "JSON Schema every file must satisfy")
+	o.keepRefs = true
+	if err := o.flags.Parse(args); err != nil {
+		return err
+	}
+	if o.flags.NArg() == 0 {
+		return fmt.Errorf("validate: at least one file is required")
+	}
+
+	var schema *ascanius.SchemaValidator
+	if *schemaPath != "" {
+		var err error
+		if schema, err = ascanius.CompileSchemaFile(*schemaPath); err != nil {
+			return err
+		}
+	}
+
+	failed := 0
+	for _, file := range o.flags.Args() {
+		b := o.builder([]string{file})
+		if schema != nil {
+			b.ValidateSources(schema)
+		}
+		b.Merged()
+
+		if !b.HasErrs() {
+			fmt.Fprintf(stdout, "ok   %s\n", file)
+			continue
+		}
+		failed++
+		fmt.Fprintf(stdout, "FAIL %s\n", file)
+		for _, err := range b.Errs() {
+			fmt.Fprintf(stdout, "     %s\n", err)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d file(s) failed validation", failed, o.flags.NArg())
+	}
+	return nil
+}
+
+func runConvert(args []string, stdout, stderr io.Writer) error {
+	o := newOptions("convert", stderr, "yaml")
+	out := o.flags.String("out", "", "write to this file instead of stdout")
+	// values are written as they are in the file: references stay
+	// unresolved and encrypted values encrypted
+	o.reveal, o.keepRefs = true, true
+	if err := o.flags.Parse(args); err != nil {
+		return err
+	}
+	if o.flags.NArg() != 1 {
+		return fmt.Errorf("convert: exactly one input file is required")
+	}
+
+	b := o.builder(o.flags.Args())
+	b.Merged()
+	if err := loadErrors(b, stderr); err != nil {
+		return err
+	}
+
+	if *out == "" {
+		return b.Export(stdout, o.format)
+	}
+	var buf bytes.Buffer
+	if err := b.Export(&buf, o.format); err != nil {
+		return err
+	}
+	perm := os.FileMode(0o644)
+	if info, err := os.Stat(*out); err == nil {
+		perm = info.Mode().Perm()
+	}
+	return atomicfile.WriteFile(*out, buf.Bytes(), perm)
+}
+
+func runEncrypt(args []string, stdout, stderr io.Writer) error {
+	o := newOptions("encrypt", stderr, "")
+	keys := addKeyFlags(o.flags, "", "to encrypt with")
+	if err := o.flags.Parse(args); err != nil {
+		return err
+	}
+	if o.flags.NArg() > 1 {
+		return fmt.Errorf("encrypt: usage: encrypt [key flags] [value|-]")
+	}
+	provider, err := keys.provider()
+	if err != nil {
+		return err
+	}
+	plaintext, err := valueArg(o.flags)
+	if err != nil {
+		return err
+	}
+
+	value, err := ascanius.EncryptValue(provider, plaintext)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, value)
+	return err
+}
+
+func runDecrypt(args []string, stdout, stderr io.Writer) error {
+	o := newOptions("decrypt", stderr, "")
+	keys := addKeyFlags(o.flags, "", "to decrypt with")
+	if err := o.flags.Parse(args); err != nil {
+		return err
+	}
+	if o.flags.NArg() > 1 {
+		return fmt.Errorf("decrypt: usage: decrypt [key flags] [value|-]")
+	}
+	encrypted, err := valueArg(o.flags)
+	if err != nil {
+		return err
+	}
+
+	value, err := ascanius.DecryptValue(encrypted, keys.providers()...)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, value)
+	return err
+}
+
+// valueArg returns the value argument of encrypt and decrypt. Without one, or
+// with "-", the value is read from stdin, so that secrets do not end up in
+// the shell history or the process list. One trailing newline is dropped.
+func valueArg(flags *flag.FlagSet) (string, error) {
+	if flags.NArg() == 1 && flags.Arg(0) != "-" {
+		return flags.Arg(0), nil
+	}
+	raw, err := io.ReadAll(stdin)
+	if err != nil {
+		return "", fmt.Errorf("reading stdin: %w", err)
+	}
+	value := strings.TrimSuffix(strings.TrimSuffix(string(raw), "\n"), "\r")
+	if value == "" {
+		return "", fmt.Errorf("no value given on stdin")
+	}
+	return value, nil
+}
+
+func runRotate(args []string, stdout, stderr io.Writer) error {
+	o := newOptions("rotate", stderr, "")
+	oldKeys := addKeyFlags(o.flags, "", "currently used")
+	newKeys := addKeyFlags(o.flags, "new-", "to rotate to")
+	if err := o.flags.Parse(args); err != nil {
+		return err
+	}
+	if o.flags.NArg() == 0 {
+		return fmt.Errorf("rotate: at least one file is required")
+	}
+	to, err := newKeys.provider()
+	if err != nil {
+		return fmt.Errorf("rotate: new key: %w", err)
+	}
+
+	for _, file := range o.flags.Args() {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		rotated, count, err := ascanius.RotateValues(content, to, oldKeys.providers()...)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		if count > 0 {
+			if err := atomicfile.Rewrite(file, rotated); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(stdout, "%s: rotated %d value(s)\n", file, count)
+	}
+	return nil
+}
+
+// migrationStep is one version upgrade in a -steps file. Keys are moved,
+// then set, then deleted.
+type migrationStep struct {
+	Move   map[string]string `yaml:"move"`
+	Set    map[string]any    `yaml:"set"`
+	Delete []string          `yaml:"delete"`
+}
+
+func (s migrationStep) migration() ascanius.Migration {
+	var steps []ascanius.Migration
+	for _, from := range sortedKeys(s.Move) {
+		steps = append(steps, ascanius.MoveKey(from, s.Move[from]))
+	}
+	for _, key := range sortedKeys(s.Set) {
+		steps = append(steps, ascanius.SetKey(key, s.Set[key]))
+	}
+	steps = append(steps, ascanius.DeleteKey(s.Delete...))
+	return ascanius.Steps(steps...)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func readMigrations(path string) ([]ascanius.Migration, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var steps []migrationStep
+	if err := yaml.Unmarshal(raw, &steps); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	migrations := make([]ascanius.Migration, len(steps))
+	for i, step := range steps {
+		migrations[i] = step.migration()
+	}
+	return migrations, nil
+}
+
+func runMigrate(args []string, stdout, stderr io.Writer) error {
+	o := newOptions("migrate", stderr, "")
+	stepsPath := o.flags.String("steps", "", "YAML or JSON list of migration steps, the first upgrading version 1 to 2")
+	if err := o.flags.Parse(args); err != nil {
+		return err
+	}
+	if *stepsPath == "" || o.flags.NArg() == 0 {
+		return fmt.Errorf("migrate: usage: migrate -steps <file> <file>...")
+	}
+
+	migrations, err := readMigrations(*stepsPath)
+	if err != nil {
+		return err
+	}
+	for _, file := range o.flags.Args() {
+		from, to, err := ascanius.MigrateFile(file, migrations...)
+		if err != nil {
+			return err
+		}
+		if from == to {
+			fmt.Fprintf(stdout, "%s: already at version %d\n", file, to)
+			continue
+		}
+		fmt.Fprintf(stdout, "%s: migrated from version %d to %d\n", file, from, to)
+	}
+	return nil
+}
+
+type sourceList []string
+
+func (s *sourceList) String() string {
+	return fmt.Sprint(*s)
+}
+
+func (s *sourceList) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+func runDiff(args []string, stdout, stderr io.Writer) error {
+	o := newOptions("diff", stderr, "")
+	o.flags.StringVar(&o.format, "o", "text", "output format: text or json")
+	var from, to sourceList
+	o.flags.Var(&from, "from", "source of the base configuration, repeat to layer")
+	o.flags.Var(&to, "to", "source of the compared configuration, repeat to layer")
+	if err := o.flags.Parse(args); err != nil {
+		return err
+	}
+	if len(from) == 0 || len(to) == 0 {
+		return fmt.Errorf("diff: at least one -from and one -to source are required")
+	}
+
+	base, target := o.builder(from), o.builder(to)
+	changes := ascanius.Diff(base, target)
+	if err := loadErrors(base, stderr); err != nil {
+		return err
+	}
+	if err := loadErrors(target, stderr); err != nil {
+		return err
+	}
+	return ascanius.WriteDiff(stdout, changes, o.format)
+}
+
+func printValue(w io.Writer, value any) error {
+	if s, ok := value.(string); ok {
+		_, err := fmt.Fprintln(w, s)
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(value)
+}
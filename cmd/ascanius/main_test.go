@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func runCmd(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestMergeAndGet(t *testing.T) {
+	t.Setenv("APP__MONGO__HOST", "env.mongo.local")
+
+	code, out, _ := runCmd(t, "get", "mongo.host", "../../files/config.toml", "env")
+	require.Equal(t, 0, code)
+	require.Equal(t, "env.mongo.local\n", out)
+
+	code, out, _ = runCmd(t, "get", "mongo.replicaSet", "../../files/config.toml")
+	require.Equal(t, 0, code)
+	require.Equal(t, "rs0\n", out)
+
+	code, out, _ = runCmd(t, "get", "mongo.password", "../../files/config.toml")
+	require.Equal(t, 0, code)
+	require.Equal(t, "******\n", out)
+
+	code, out, _ = runCmd(t, "merge", "-o", "json", "../../files/flat.toml", "../../files/flat.json")
+	require.Equal(t, 0, code)
+	require.JSONEq(t, `{"host": "localhost", "port": 9090}`, out)
+
+	code, _, _ = runCmd(t, "get", "missing", "../../files/flat.toml")
+	require.Equal(t, 1, code)
+}
+
+func TestExplain(t *testing.T) {
+	code, out, _ := runCmd(t, "explain", "port", "../../files/flat.toml", "../../files/flat.json")
+	require.Equal(t, 0, code)
+	require.Equal(t, "port:\n  [1] ../../files/flat.toml = 8080\n* [2] ../../files/flat.json = 9090\n", out)
+}
+
+func TestValidate(t *testing.T) {
+	code, out, _ := runCmd(t, "validate", "../../files/config.toml", "../../files/mongo.yaml")
+	require.Equal(t, 0, code)
+	require.Contains(t, out, "ok   ../../files/mongo.yaml")
+
+	code, out, stderr := runCmd(t, "validate", "../../files/config.toml", "../../files/bad.json")
+	require.Equal(t, 1, code)
+	require.Contains(t, out, "FAIL ../../files/bad.json")
+	require.Contains(t, stderr, "1 of 2 file(s) failed validation")
+}
+
+func TestConvert(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "config.json")
+	code, _, stderr := runCmd(t, "convert", "-o", "json", "-out", out, "../../files/mongo.yaml")
+	require.Equal(t, 0, code, stderr)
+
+	converted, err := os.ReadFile(out)
+	require.NoError(t, err)
+	require.Contains(t, string(converted), `"password": "toml-pass"`)
+	require.Contains(t, string(converted), `"replica_set": "rs0"`)
+
+	code, _, _ = runCmd(t, "unknown")
+	require.Equal(t, 2, code)
+}
+
+func TestConvertKeepsReferences(t *testing.T) {
+	t.Setenv("MONGO_USER", "admin")
+	dir := t.TempDir()
+	key := filepath.Join(dir, "config.key")
+	require.NoError(t, os.WriteFile(key, bytes.Repeat([]byte{1}, 32), 0o600))
+	_, encrypted, _ := runCmd(t, "encrypt", "-key-file", key, "s3cr3t")
+
+	config := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(config, []byte("user: ref+env://MONGO_USER\npassword: "+encrypted), 0o644))
+
+	code, out, stderr := runCmd(t, "convert", "-o", "json", config)
+	require.Equal(t, 0, code, stderr)
+	require.JSONEq(t, `{"user": "ref+env://MONGO_USER", "password": "`+strings.TrimSpace(encrypted)+`"}`, out)
+
+	code, out, _ = runCmd(t, "merge", config)
+	require.Equal(t, 0, code)
+	require.Contains(t, out, "ref+env://MONGO_USER")
+
+	// a failed conversion leaves the target untouched
+	code, _, _ = runCmd(t, "convert", "-o", "xml", "-out", config, config)
+	require.Equal(t, 1, code)
+	content, err := os.ReadFile(config)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "ref+env://MONGO_USER")
+}
+
+func TestDiff(t *testing.T) {
+	code, out, _ := runCmd(t, "diff", "-from", "../../files/flat.toml", "-to", "../../files/flat.toml", "-to", "../../files/flat.json")
+	require.Equal(t, 0, code)
+	require.Equal(t, "~ port: 8080 -> 9090\n", out)
+}
+
+func TestEncryptDecryptRotate(t *testing.T) {
+	dir := t.TempDir()
+	oldKey := filepath.Join(dir, "old.key")
+	newKey := filepath.Join(dir, "new.key")
+	require.NoError(t, os.WriteFile(oldKey, bytes.Repeat([]byte{1}, 32), 0o600))
+	require.NoError(t, os.WriteFile(newKey, bytes.Repeat([]byte{2}, 32), 0o600))
+
+	code, value, _ := runCmd(t, "encrypt", "-key-file", oldKey, "s3cr3t")
+	require.Equal(t, 0, code)
+
+	config := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(config, []byte("password: "+value), 0o644))
+
+	code, out, _ := runCmd(t, "rotate", "-key-file", oldKey, "-new-key-file", newKey, config)
+	require.Equal(t, 0, code)
+	require.Equal(t, config+": rotated 1 value(s)\n", out)
+
+	code, out, _ = runCmd(t, "merge", "-key-file", newKey, "-reveal", config)
+	require.Equal(t, 0, code)
+	require.JSONEq(t, `{"password": "s3cr3t"}`, out)
+
+	code, _, stderr := runCmd(t, "merge", "-key-file", oldKey, config)
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr, "cannot decrypt password")
+}
+
+func TestEncryptFromStdin(t *testing.T) {
+	dir := t.TempDir()
+	key := filepath.Join(dir, "config.key")
+	require.NoError(t, os.WriteFile(key, bytes.Repeat([]byte{1}, 32), 0o600))
+	t.Cleanup(func() { stdin = os.Stdin })
+
+	stdin = strings.NewReader("s3cr3t\n")
+	code, value, stderr := runCmd(t, "encrypt", "-key-file", key)
+	require.Equal(t, 0, code, stderr)
+
+	stdin = strings.NewReader(value)
+	code, out, stderr := runCmd(t, "decrypt", "-key-file", key, "-")
+	require.Equal(t, 0, code, stderr)
+	require.Equal(t, "s3cr3t\n", out)
+
+	stdin = strings.NewReader("")
+	code, _, stderr = runCmd(t, "encrypt", "-key-file", key)
+	require.Equal(t, 1, code)
+	require.Contains(t, stderr, "no value given on stdin")
+
+	// rotating a private file keeps it private
+	config := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(config, []byte("password: "+value), 0o600))
+	newKey := filepath.Join(dir, "new.key")
+	require.NoError(t, os.WriteFile(newKey, bytes.Repeat([]byte{2}, 32), 0o600))
+	code, _, stderr = runCmd(t, "rotate", "-key-file", key, "-new-key-file", newKey, config)
+	require.Equal(t, 0, code, stderr)
+	info, err := os.Stat(config)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestMigrate(t *testing.T) {
+	dir := t.TempDir()
+	steps := filepath.Join(dir, "migrations.yaml")
+	require.NoError(t, os.WriteFile(steps, []byte(`
+- move:
+    mongo_host: mongo.host
+- delete: [legacy]
+  set:
+    mongo.tls: true
+`), 0o644))
+	config := filepath.Join(dir, "config.json")
+	require.NoError(t, os.WriteFile(config, []byte(`{"version": 1, "mongo_host": "db", "legacy": true}`), 0o644))
+
+	code, out, stderr := runCmd(t, "migrate", "-steps", steps, config)
+	require.Equal(t, 0, code, stderr)
+	require.Equal(t, config+": migrated from version 1 to 3\n", out)
+
+	migrated, err := os.ReadFile(config)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"version": 3, "mongo": {"host": "db", "tls": true}}`, string(migrated))
+
+	code, out, _ = runCmd(t, "migrate", "-steps", steps, config)
+	require.Equal(t, 0, code)
+	require.Equal(t, config+": already at version 3\n", out)
+
+	code, _, _ = runCmd(t, "migrate", config)
+	require.Equal(t, 1, code)
+}
@@ -0,0 +1,150 @@
+package ascanius
+
+import (
+	"errors"
+	"log/slog"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Config is a read-only view of the merged configuration for code that
+// reads keys directly instead of binding a struct, such as plugins. Keys are
+// dotted paths normalized like Lookup's. Values are converted with the rules
+// of struct binding.
+//
+// A view is a snapshot: it is not affected by later loads.
+type Config struct {
+	b    *Builder
+	data map[string]any
+	// path is the key path of data in the merged configuration
+	path string
+}
+
+// Config returns a read-only view of the merged configuration, loading it
+// first if nothing has been loaded yet.
+func (b *Builder) Config() *Config {
+	b.mu.Lock()
+	defer b.unlock()
+	return &Config{b: b, data: deepCopyMap(b.current())}
+}
+
+// Get returns the value at key as it was merged.
+func (c *Config) Get(key string) (any, bool) {
+	return lookupPath(c.data, normalizeKeyPath(key))
+}
+
+// IsSet reports whether key has a value.
+func (c *Config) IsSet(key string) bool {
+	_, ok := c.Get(key)
+	return ok
+}
+
+// getAs converts the value at key to T, returning the zero value when key
+// is not set or cannot be converted.
+func getAs[T any](c *Config, key string) T {
+	var zero T
+	value, ok := c.Get(key)
+	if !ok {
+		return zero
+	}
+	converted, err := convertValue(value, reflect.TypeFor[T]())
+	if err != nil || !converted.IsValid() {
+		return zero
+	}
+	return converted.Interface().(T)
+}
+
+// GetString returns the value at key as a string, or "" when it is not set
+// or cannot be converted. Use IsSet to tell the two apart.
+func (c *Config) GetString(key string) string {
+	return getAs[string](c, key)
+}
+
+// GetInt returns the value at key as an int, or 0.
+func (c *Config) GetInt(key string) int {
+	return getAs[int](c, key)
+}
+
+// GetBool returns the value at key as a bool, or false.
+func (c *Config) GetBool(key string) bool {
+	return getAs[bool](c, key)
+}
+
+// GetDuration returns the value at key, e.g. "1m30s", as a time.Duration,
+// or 0.
+func (c *Config) GetDuration(key string) time.Duration {
+	return getAs[time.Duration](c, key)
+}
+
+// GetStringSlice returns the value at key as a []string, or nil.
+func (c *Config) GetStringSlice(key string) []string {
+	return getAs[[]string](c, key)
+}
+
+// Keys returns the sorted keys directly under prefix. An empty prefix lists
+// the top-level keys.
+func (c *Config) Keys(prefix string) []string {
+	value, ok := lookupPath(c.data, normalizeKeyPath(prefix))
+	m, isMap := value.(map[string]any)
+	if !ok || !isMap {
+		return nil
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Sub returns a view of the keys under prefix. It is empty when prefix does
+// not hold a section.
+func (c *Config) Sub(prefix string) *Config {
+	prefix = normalizeKeyPath(prefix)
+	value, _ := lookupPath(c.data, prefix)
+	data, ok := value.(map[string]any)
+	if !ok {
+		data = map[string]any{}
+	}
+	return &Config{b: c.b, data: data, path: joinKey(c.path, prefix)}
+}
+
+// Unmarshal binds the section at prefix into target, a pointer to a struct,
+// the way Load does. An empty prefix binds the whole view.
+func (c *Config) Unmarshal(prefix string, target any) error {
+	prefix = normalizeKeyPath(prefix)
+	value, ok := lookupPath(c.data, prefix)
+	data, isMap := value.(map[string]any)
+	if !ok || !isMap {
+		return &SectionNotFoundError{Section: joinKey(c.path, prefix)}
+	}
+	if target == nil {
+		return &BindError{Err: errors.New("target cannot be nil")}
+	}
+
+	b := c.b
+	b.mu.Lock()
+	defer b.unlock()
+	// binding errors are returned rather than kept with the load errors
+	loadErrs := len(b.errs)
+	err := b.applyValues(target, data, joinKey(c.path, prefix))
+	errs := append(append([]error{}, b.errs[loadErrs:]...), err)
+	b.errs = b.errs[:loadErrs]
+	return errors.Join(errs...)
+}
+
+// String returns the view as JSON with secret values masked.
+func (c *Config) String() string {
+	return c.redacted().String()
+}
+
+func (c *Config) LogValue() slog.Value {
+	return c.redacted().LogValue()
+}
+
+func (c *Config) redacted() RedactedConfig {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+	return RedactedConfig{data: c.b.redact(c.data, c.path, nil)}
+}
@@ -0,0 +1,86 @@
+package ascanius
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pluginConfig() *Builder {
+	return New().AddSource(&stubSource{name: "stub", priority: 1, data: map[string]any{
+		"name": "api",
+		"port": "8080",
+		"plugins": map[string]any{
+			"auth": map[string]any{
+				"issuer":   "https://issuer",
+				"enabled":  "true",
+				"ttl":      "15m",
+				"audience": []any{"web", "cli"},
+				"password": "hunter2",
+			},
+			"cache": map[string]any{"size": 128},
+		},
+	}})
+}
+
+func TestConfigGetters(t *testing.T) {
+	cfg := pluginConfig().Config()
+
+	assert.Equal(t, "https://issuer", cfg.GetString("plugins.auth.issuer"))
+	assert.Equal(t, 8080, cfg.GetInt("port"))
+	assert.True(t, cfg.GetBool("plugins.auth.enabled"))
+	assert.Equal(t, 15*time.Minute, cfg.GetDuration("plugins.auth.ttl"))
+	assert.Equal(t, []string{"web", "cli"}, cfg.GetStringSlice("plugins.auth.audience"))
+	assert.Equal(t, "128", cfg.GetString("plugins.cache.size"))
+
+	// missing and unconvertible values read as zero
+	assert.Equal(t, 0, cfg.GetInt("name"))
+	assert.Equal(t, "", cfg.GetString("missing"))
+	assert.False(t, cfg.IsSet("missing"))
+	assert.True(t, cfg.IsSet("plugins.auth"))
+}
+
+func TestConfigKeysAndSub(t *testing.T) {
+	cfg := pluginConfig().Config()
+	assert.Equal(t, []string{"name", "plugins", "port"}, cfg.Keys(""))
+	assert.Equal(t, []string{"auth", "cache"}, cfg.Keys("plugins"))
+	assert.Nil(t, cfg.Keys("name"))
+
+	auth := cfg.Sub("plugins").Sub("auth")
+	assert.Equal(t, "https://issuer", auth.GetString("issuer"))
+	assert.False(t, auth.IsSet("name"))
+	assert.NotContains(t, auth.String(), "hunter2")
+	assert.Empty(t, cfg.Sub("missing").Keys(""))
+}
+
+func TestConfigUnmarshal(t *testing.T) {
+	type auth struct {
+		Issuer   string
+		Enabled  bool
+		Ttl      time.Duration
+		Audience []string
+		Retries  int `def:"3"`
+	}
+	b := pluginConfig()
+	cfg := b.Config()
+
+	var a auth
+	require.NoError(t, cfg.Sub("plugins").Unmarshal("auth", &a))
+	assert.Equal(t, auth{"https://issuer", true, 15 * time.Minute, []string{"web", "cli"}, 3}, a)
+
+	type cache struct {
+		Size int8
+	}
+	var c cache
+	err := cfg.Unmarshal("plugins.cache", &c)
+	var bindErr *BindError
+	require.ErrorAs(t, err, &bindErr)
+	assert.Equal(t, "plugins.cache.size", bindErr.Key)
+	assert.False(t, b.HasErrs(), "Unmarshal errors are not load errors")
+
+	var notFound *SectionNotFoundError
+	assert.ErrorAs(t, cfg.Unmarshal("plugins.missing", &c), &notFound)
+	assert.Error(t, cfg.Unmarshal("", c))
+}
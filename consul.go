@@ -0,0 +1,99 @@
+package ascanius
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DEFAULT_CONSUL_WAIT = 5 * time.Minute
+
+// ConsulClient is a KVStore speaking the Consul KV HTTP API
+// (GET /v1/kv/<prefix>?recurse with blocking queries).
+type ConsulClient struct {
+	address string
+	token   string
+	wait    time.Duration
+	client  *http.Client
+}
+
+type consulPair struct {
+	Key         string
+	Value       []byte // base64 in the JSON payload
+	ModifyIndex uint64
+}
+
+// NewConsulClient returns a client for the agent at address, e.g.
+// "http://127.0.0.1:8500". An empty token sends no ACL token.
+func NewConsulClient(address string, token string) *ConsulClient {
+	return &ConsulClient{
+		address: strings.TrimRight(address, "/"),
+		token:   token,
+		wait:    DEFAULT_CONSUL_WAIT,
+		client:  &http.Client{},
+	}
+}
+
+// WithWait sets the maximum duration of blocking Watch queries.
+func (c *ConsulClient) WithWait(wait time.Duration) *ConsulClient {
+	c.wait = wait
+	return c
+}
+
+func (c *ConsulClient) List(ctx context.Context, prefix string) ([]KVPair, error) {
+	pairs, _, err := c.get(ctx, prefix, nil)
+	return pairs, err
+}
+
+func (c *ConsulClient) Watch(ctx context.Context, prefix string, index uint64) ([]KVPair, uint64, error) {
+	query := url.Values{}
+	query.Set("index", strconv.FormatUint(index, 10))
+	query.Set("wait", c.wait.String())
+	return c.get(ctx, prefix, query)
+}
+
+func (c *ConsulClient) get(ctx context.Context, prefix string, query url.Values) ([]KVPair, uint64, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("recurse", "true")
+
+	endpoint := c.address + "/v1/kv/" + strings.TrimLeft(prefix, "/") + "?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if c.token != "" {
+		req.Header.Set("X-Consul-Token", c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	index, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, index, nil
+	default:
+		return nil, 0, fmt.Errorf("GET %s: %s", req.URL.Path, resp.Status)
+	}
+
+	var raw []consulPair
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, 0, fmt.Errorf("invalid response from %s: %w", req.URL.Path, err)
+	}
+	pairs := make([]KVPair, len(raw))
+	for i, p := range raw {
+		pairs[i] = KVPair(p)
+	}
+	return pairs, index, nil
+}
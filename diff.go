@@ -0,0 +1,251 @@
+package ascanius
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+)
+
+type ChangeKind string
+
+const (
+	DIFF_ADDED   ChangeKind = "added"
+	DIFF_REMOVED ChangeKind = "removed"
+	DIFF_CHANGED ChangeKind = "changed"
+)
+
+// Change is a single key-path level difference between two configurations.
+type Change struct {
+	Key         string     `json:"key"`
+	Kind        ChangeKind `json:"kind"`
+	Old         any        `json:"old,omitempty"`
+	New         any        `json:"new,omitempty"`
+	OldType     string     `json:"old_type,omitempty"`
+	NewType     string     `json:"new_type,omitempty"`
+	TypeChanged bool       `json:"type_changed,omitempty"`
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case DIFF_ADDED:
+		return fmt.Sprintf("+ %s = %s", c.Key, diffValue(c.New))
+	case DIFF_REMOVED:
+		return fmt.Sprintf("- %s = %s", c.Key, diffValue(c.Old))
+	default:
+		s := fmt.Sprintf("~ %s: %s -> %s", c.Key, diffValue(c.Old), diffValue(c.New))
+		if c.TypeChanged {
+			s += fmt.Sprintf(" (type %s -> %s)", c.OldType, c.NewType)
+		}
+		return s
+	}
+}
+
+// Diff merges the sources of both builders and returns the differences from
+// one configuration to the other, sorted by key. Keys are compared after
+// snake_case normalization and secret values are masked using the secret
+// rules of either builder.
+func Diff(from, to *Builder) []Change {
+	var changes []Change
+	diffMaps(&changes, "", from.Merged(), to.Merged())
+
+	for i, c := range changes {
+		if from.isSecretPath(c.Key) || to.isSecretPath(c.Key) {
+			if c.Old != nil {
+				changes[i].Old = SECRET_MASK
+			}
+			if c.New != nil {
+				changes[i].New = SECRET_MASK
+			}
+			continue
+		}
+		// maps and arrays replaced by other values may hold secrets
+		changes[i].Old = to.redactedValue(from.redactedValue(c.Old, c.Key), c.Key)
+		changes[i].New = to.redactedValue(from.redactedValue(c.New, c.Key), c.Key)
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// WriteDiff writes changes to w, either as human readable lines ("text")
+// or as a JSON array ("json").
+func WriteDiff(w io.Writer, changes []Change, format string) error {
+	switch format {
+	case "", "text":
+		for _, c := range changes {
+			if _, err := fmt.Fprintln(w, c.String()); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case JSON_SOURCE_NAME:
+		if changes == nil {
+			changes = []Change{}
+		}
+		out, err := marshalJson(changes)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(out)
+		return err
+
+	default:
+		return fmt.Errorf("unsupported diff format %s", format)
+	}
+}
+
+func diffMaps(changes *[]Change, parent string, from, to map[string]any) {
+	for k, oldVal := range from {
+		key := joinKey(parent, k)
+		newVal, ok := to[k]
+		if !ok {
+			diffMissing(changes, key, oldVal, DIFF_REMOVED)
+			continue
+		}
+		diffValues(changes, key, oldVal, newVal)
+	}
+	for k, newVal := range to {
+		if _, ok := from[k]; !ok {
+			diffMissing(changes, joinKey(parent, k), newVal, DIFF_ADDED)
+		}
+	}
+}
+
+func diffMissing(changes *[]Change, key string, value any, kind ChangeKind) {
+	if sub, ok := value.(map[string]any); ok && len(sub) > 0 {
+		for k, v := range sub {
+			diffMissing(changes, joinKey(key, k), v, kind)
+		}
+		return
+	}
+
+	c := Change{Key: key, Kind: kind}
+	if kind == DIFF_ADDED {
+		c.New, c.NewType = value, jsonType(value)
+	} else {
+		c.Old, c.OldType = value, jsonType(value)
+	}
+	*changes = append(*changes, c)
+}
+
+func diffValues(changes *[]Change, key string, oldVal, newVal any) {
+	oldMap, oldIsMap := oldVal.(map[string]any)
+	newMap, newIsMap := newVal.(map[string]any)
+	if oldIsMap && newIsMap {
+		diffMaps(changes, key, oldMap, newMap)
+		return
+	}
+
+	if sameValue(oldVal, newVal) {
+		return
+	}
+
+	oldType, newType := jsonType(oldVal), jsonType(newVal)
+	*changes = append(*changes, Change{
+		Key:         key,
+		Kind:        DIFF_CHANGED,
+		Old:         oldVal,
+		New:         newVal,
+		OldType:     oldType,
+		NewType:     newType,
+		TypeChanged: oldType != newType,
+	})
+}
+
+// sameValue reports whether a and b are deeply equal, comparing numbers by
+// value so that values decoded by different parsers (int64 from TOML,
+// float64 from JSON) compare equal. Integers are compared exactly.
+func sameValue(a, b any) bool {
+	switch av := a.(type) {
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, item := range av {
+			other, ok := bv[k]
+			if !ok || !sameValue(item, other) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !sameValue(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	x, aIsNumber := exactNumber(a)
+	y, bIsNumber := exactNumber(b)
+	if aIsNumber && bIsNumber {
+		return x.Cmp(y) == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// exactNumber returns v as an exact big.Float when it is a number other than
+// NaN.
+func exactNumber(v any) (*big.Float, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return new(big.Float).SetInt64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return new(big.Float).SetUint64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		if math.IsNaN(rv.Float()) {
+			return nil, false
+		}
+		return new(big.Float).SetFloat64(rv.Float()), true
+	default:
+		return nil, false
+	}
+}
+
+func jsonType(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	}
+
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func diffValue(v any) string {
+	out, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(out)
+}
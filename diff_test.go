@@ -0,0 +1,90 @@
+package ascanius
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	dir := t.TempDir()
+	prod := filepath.Join(dir, "prod.json")
+	require.NoError(t, os.WriteFile(prod, []byte(`{
+		"mongo": {
+			"Scheme": "mongodb+srv",
+			"host": "prod.mongo.local",
+			"port": "27018",
+			"password": "prod-pass",
+			"pool": {"size": 10}
+		}
+	}`), 0o644))
+
+	staging := New().Source("./files/config.toml", 1)
+	production := New().Source("./files/config.toml", 1).Source(prod, 2)
+
+	changes := Diff(staging, production)
+	require.Equal(t, []Change{
+		{Key: "mongo.host", Kind: DIFF_CHANGED, Old: "mongo.example.com", New: "prod.mongo.local", OldType: "string", NewType: "string"},
+		{Key: "mongo.password", Kind: DIFF_CHANGED, Old: SECRET_MASK, New: SECRET_MASK, OldType: "string", NewType: "string"},
+		{Key: "mongo.pool.size", Kind: DIFF_ADDED, New: int64(10), NewType: "number"},
+		{Key: "mongo.port", Kind: DIFF_CHANGED, Old: int64(27018), New: "27018", OldType: "number", NewType: "string", TypeChanged: true},
+	}, changes)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteDiff(&buf, changes, "text"))
+	require.Equal(t, `~ mongo.host: "mongo.example.com" -> "prod.mongo.local"
+~ mongo.password: "******" -> "******"
++ mongo.pool.size = 10
+~ mongo.port: 27018 -> "27018" (type number -> string)
+`, buf.String())
+
+	require.Empty(t, Diff(New().Source("./files/mongo.yaml", 1), New().Source("./files/mongo.json", 1)))
+
+	buf.Reset()
+	require.NoError(t, WriteDiff(&buf, nil, "json"))
+	require.Equal(t, "[]\n", buf.String())
+}
+
+func TestDiffMasksNestedSecrets(t *testing.T) {
+	from := New().AddSource(&stubSource{name: "from", priority: 1, data: map[string]any{
+		"mongo":   map[string]any{"host": "h", "password": "hunter2"},
+		"servers": []any{map[string]any{"name": "a", "token": "t0ken"}},
+	}})
+	to := New().AddSource(&stubSource{name: "to", priority: 1, data: map[string]any{
+		"mongo":   "mongodb://x",
+		"servers": "none",
+	}})
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteDiff(&buf, Diff(from, to), "text"))
+	require.Equal(t, `~ mongo: {"host":"h","password":"******"} -> "mongodb://x" (type object -> string)
+~ servers: [{"name":"a","token":"******"}] -> "none" (type array -> string)
+`, buf.String())
+	require.NotContains(t, buf.String(), "hunter2")
+
+	// the reverse direction masks the new value
+	buf.Reset()
+	require.NoError(t, WriteDiff(&buf, Diff(to, from), "text"))
+	require.NotContains(t, buf.String(), "hunter2")
+	require.NotContains(t, buf.String(), "t0ken")
+}
+
+func TestDiffComparesIntegersExactly(t *testing.T) {
+	from := New().AddSource(&stubSource{name: "from", priority: 1, data: map[string]any{
+		"id":    int64(9007199254740993),
+		"ratio": int64(2),
+		"ids":   []any{int64(1), uint64(1 << 63)},
+	}})
+	to := New().AddSource(&stubSource{name: "to", priority: 1, data: map[string]any{
+		"id":    int64(9007199254740992),
+		"ratio": float64(2),
+		"ids":   []any{float64(1), uint64(1 << 63)},
+	}})
+
+	require.Equal(t, []Change{
+		{Key: "id", Kind: DIFF_CHANGED, Old: int64(9007199254740993), New: int64(9007199254740992), OldType: "number", NewType: "number"},
+	}, Diff(from, to))
+}
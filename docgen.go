@@ -0,0 +1,170 @@
+package ascanius
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// fieldDoc describes a leaf config field as Load resolves it.
+type fieldDoc struct {
+	Path        []string
+	Type        reflect.Type
+	Default     string
+	Description string
+	Secret      bool
+}
+
+// EnvExample writes a .env.example file for target listing every leaf
+// variable with its default, using the builder's prefix and separator.
+// Descriptions from `description` tags are written as comments. Fields
+// whose default Load cannot apply are written without a value.
+func (b *Builder) EnvExample(w io.Writer, target any) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	docs, err := collectFieldDocs(target)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for _, doc := range docs {
+		value := ""
+		if parsed, err := parseDefault(doc.Default, doc.Type); doc.Default != "" && err == nil {
+			// durations and text types are read back from their text
+			var v any = parsed.Interface()
+			if isTextType(doc.Type) {
+				v = textDefault(parsed, doc.Default)
+			}
+			if value, err = formatDotenvValue(v); err != nil {
+				return err
+			}
+		}
+
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		if doc.Description != "" {
+			fmt.Fprintf(&buf, "# %s\n", doc.Description)
+		}
+		if doc.Secret {
+			buf.WriteString("# secret\n")
+		}
+		fmt.Fprintf(&buf, "%s=%s\n", b.envName(doc.Path), value)
+	}
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// Reference writes a Markdown table documenting every leaf key of target:
+// its key path, environment variable, type, default and description.
+func (b *Builder) Reference(w io.Writer, target any) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	docs, err := collectFieldDocs(target)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("| Key | Env var | Type | Default | Description |\n")
+	buf.WriteString("| --- | --- | --- | --- | --- |\n")
+	for _, doc := range docs {
+		typ := doc.Type.String()
+		if doc.Secret {
+			typ += " (secret)"
+		}
+		def := ""
+		if doc.Default != "" {
+			def = "`" + doc.Default + "`"
+		}
+		fmt.Fprintf(&buf, "| `%s` | `%s` | `%s` | %s | %s |\n",
+			strings.Join(doc.Path, "."),
+			b.envName(doc.Path),
+			typ,
+			markdownCell(def),
+			markdownCell(doc.Description),
+		)
+	}
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// envName returns the variable EnvSource reads for path, which always
+// starts with the prefix and separator, even when the prefix is empty.
+func (b *Builder) envName(path []string) string {
+	return b.envPrefix + b.envSep + strings.ToUpper(strings.Join(path, b.envSep))
+}
+
+func collectFieldDocs(target any) ([]fieldDoc, error) {
+	if target == nil {
+		return nil, errors.New("target cannot be nil")
+	}
+	typ := reflect.TypeOf(target)
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, errors.New("target must be a struct or pointer to a struct")
+	}
+
+	var docs []fieldDoc
+	collectStructDocs(&docs, typ, nil, map[reflect.Type]bool{})
+	return docs, nil
+}
+
+// collectStructDocs appends the leaf fields of typ. visiting holds the
+// structs being descended into, so that recursive types stop at the first
+// repetition.
+func collectStructDocs(docs *[]fieldDoc, typ reflect.Type, parent []string, visiting map[reflect.Type]bool) {
+	visiting[typ] = true
+	defer delete(visiting, typ)
+
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		key := field.Tag.Get("cfg")
+		if key == "" {
+			key = toSnakeCase(field.Name)
+		}
+		path := append(append([]string{}, parent...), key)
+
+		fieldType := field.Type
+		secret := isSecretField(field)
+		if secret && reflect.PointerTo(fieldType).Implements(secretHolderType) {
+			fieldType = reflect.New(fieldType).Interface().(secretHolder).secretValue().Type()
+		}
+
+		structType := fieldType
+		if structType.Kind() == reflect.Ptr {
+			structType = structType.Elem()
+		}
+		if structType.Kind() == reflect.Struct && !isTextType(structType) {
+			if !visiting[structType] {
+				collectStructDocs(docs, structType, path, visiting)
+			}
+			continue
+		}
+
+		*docs = append(*docs, fieldDoc{
+			Path:        path,
+			Type:        fieldType,
+			Default:     field.Tag.Get("def"),
+			Description: field.Tag.Get("description"),
+			Secret:      secret,
+		})
+	}
+}
+
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
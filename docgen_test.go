@@ -0,0 +1,110 @@
+package ascanius
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvExample(t *testing.T) {
+	type Config struct {
+		Log    LogConfig
+		Server struct {
+			Host  string `def:"localhost" description:"address to bind"`
+			Port  uint16 `def:"8080"`
+			Token Secret[string]
+		}
+	}
+
+	var buf bytes.Buffer
+	builder := New().EnvPrefix("SVC").EnvSeparator("__")
+	require.NoError(t, builder.EnvExample(&buf, &Config{}))
+
+	out := buf.String()
+	require.Contains(t, out, "SVC__LOG__LEVEL=\"error\"\n")
+	require.Contains(t, out, "SVC__LOG__OUTPUTS='[\"stdout\",\"file:logs/app.log\"]'\n")
+	require.Contains(t, out, "# address to bind\nSVC__SERVER__HOST=\"localhost\"\n")
+	require.Contains(t, out, "SVC__SERVER__PORT='8080'\n")
+	require.Contains(t, out, "# secret\nSVC__SERVER__TOKEN=\n")
+
+	path := filepath.Join(t.TempDir(), ".env.example")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+
+	var cfg Config
+	reloaded := New().EnvPrefix("SVC").Source(path, 1).Load(&cfg)
+	require.False(t, reloaded.HasErrs(), reloaded.Errs())
+	require.Equal(t, []string{"stdout", "file:logs/app.log"}, cfg.Log.Outputs)
+	require.Equal(t, "2006-01-02T15:04:05.999999999Z07:00", cfg.Log.TimestampFormat)
+	require.Equal(t, "localhost", cfg.Server.Host)
+	require.Equal(t, uint16(8080), cfg.Server.Port)
+}
+
+func TestReference(t *testing.T) {
+	type Config struct {
+		Mongo struct {
+			Host     string `def:"localhost" description:"server | host"`
+			Password string `secret:"true"`
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, New().Reference(&buf, Config{}))
+	require.Equal(t, "| Key | Env var | Type | Default | Description |\n"+
+		"| --- | --- | --- | --- | --- |\n"+
+		"| `mongo.host` | `APP__MONGO__HOST` | `string` | `localhost` | server \\| host |\n"+
+		"| `mongo.password` | `APP__MONGO__PASSWORD` | `string (secret)` |  |  |\n", buf.String())
+}
+
+type docNode struct {
+	Name string `def:"root"`
+	Next *docNode
+}
+
+func TestEnvExampleRoundTrip(t *testing.T) {
+	type Config struct {
+		Timeout  time.Duration `def:"5s"`
+		Interval time.Duration `def:"1000000000"`
+		Retries  int           `def:"3"`
+		Started  time.Time     `def:"2024-01-02T03:04:05Z"`
+		Broken   int           `def:"many"`
+		Head     docNode
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, New().EnvPrefix("").EnvExample(&buf, &Config{}))
+	require.Equal(t, "__TIMEOUT=\"5s\"\n\n"+
+		"__INTERVAL=\"1s\"\n\n"+
+		"__RETRIES='3'\n\n"+
+		"__STARTED=\"2024-01-02T03:04:05Z\"\n\n"+
+		"__BROKEN=\n\n"+
+		"__HEAD__NAME=\"root\"\n", buf.String())
+
+	path := filepath.Join(t.TempDir(), ".env.example")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+
+	data, err := NewEnvSource(path, 1, WithPrefix("")).Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"timeout":  "5s",
+		"interval": "1s",
+		"retries":  int64(3),
+		"started":  "2024-01-02T03:04:05Z",
+		"broken":   "",
+		"head":     map[string]any{"name": "root"},
+	}, data)
+
+	// the defaults are the ones Load applies
+	var defaults, reloaded Config
+	require.NoError(t, New().Load(&defaults).Err())
+	require.Equal(t, 5*time.Second, defaults.Timeout)
+	require.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), defaults.Started)
+	delete(data, "broken")
+	require.NoError(t, New().AddSource(&stubSource{name: "example", priority: 1, data: data}).Load(&reloaded).Err())
+	require.Equal(t, "root", reloaded.Head.Name)
+	reloaded.Head = defaults.Head
+	require.Equal(t, defaults, reloaded)
+}
@@ -0,0 +1,330 @@
+package ascanius
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"filippo.io/age"
+)
+
+const (
+	ENCRYPTED_PREFIX = "enc:v1:"
+	AES256GCM        = "AES256GCM"
+	AGE              = "AGE"
+)
+
+var encryptedValueRegex = regexp.MustCompile(`enc:v1:[A-Za-z0-9]+:[A-Za-z0-9+/=]+`)
+
+// KeyProvider encrypts and decrypts config values with a single algorithm.
+// Values are stored as "enc:v1:<algorithm>:<base64 ciphertext>".
+type KeyProvider interface {
+	Algorithm() string
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+type aesKeyProvider struct {
+	source string
+	key    func() ([]byte, error)
+}
+
+// NewKeyFileProvider returns an AES-256-GCM provider whose 32 byte key is read
+// from path, either raw or encoded as base64 or hex.
+func NewKeyFileProvider(path string) KeyProvider {
+	return &aesKeyProvider{
+		source: path,
+		key: func() ([]byte, error) {
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			return decodeKey(raw)
+		},
+	}
+}
+
+// NewEnvKeyProvider returns an AES-256-GCM provider whose 32 byte key is read
+// from the environment variable name, encoded as base64 or hex.
+func NewEnvKeyProvider(name string) KeyProvider {
+	return &aesKeyProvider{
+		source: "$" + name,
+		key: func() ([]byte, error) {
+			raw, ok := os.LookupEnv(name)
+			if !ok {
+				return nil, fmt.Errorf("environment variable %s is not set", name)
+			}
+			return decodeKey([]byte(raw))
+		},
+	}
+}
+
+func decodeKey(raw []byte) ([]byte, error) {
+	trimmed := strings.TrimSpace(string(raw))
+	if key, err := base64.StdEncoding.DecodeString(trimmed); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	if key, err := hex.DecodeString(trimmed); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	if len(raw) == 32 {
+		return raw, nil
+	}
+	return nil, errors.New("key must be 32 bytes, raw or encoded as base64 or hex")
+}
+
+func (p *aesKeyProvider) Algorithm() string {
+	return AES256GCM
+}
+
+func (p *aesKeyProvider) gcm() (cipher.AEAD, error) {
+	key, err := p.key()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read key %s: %w", p.source, err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (p *aesKeyProvider) Encrypt(plaintext []byte) ([]byte, error) {
+	gcm, err := p.gcm()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (p *aesKeyProvider) Decrypt(ciphertext []byte) ([]byte, error) {
+	gcm, err := p.gcm()
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, sealed, nil)
+}
+
+type ageKeyProvider struct {
+	path string
+}
+
+// NewAgeIdentityProvider returns a provider using the age X25519 identities
+// stored in path (as written by age-keygen). Values are encrypted to the
+// recipient of the first identity.
+func NewAgeIdentityProvider(path string) KeyProvider {
+	return &ageKeyProvider{path: path}
+}
+
+func (p *ageKeyProvider) identities() ([]age.Identity, error) {
+	f, err := os.Open(p.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ids, err := age.ParseIdentities(f)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read age identity %s: %w", p.path, err)
+	}
+	return ids, nil
+}
+
+func (p *ageKeyProvider) Algorithm() string {
+	return AGE
+}
+
+func (p *ageKeyProvider) Encrypt(plaintext []byte) ([]byte, error) {
+	ids, err := p.identities()
+	if err != nil {
+		return nil, err
+	}
+	id, ok := ids[0].(*age.X25519Identity)
+	if !ok {
+		return nil, fmt.Errorf("age identity %s is not an X25519 identity", p.path)
+	}
+
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, id.Recipient())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (p *ageKeyProvider) Decrypt(ciphertext []byte) ([]byte, error) {
+	ids, err := p.identities()
+	if err != nil {
+		return nil, err
+	}
+	r, err := age.Decrypt(bytes.NewReader(ciphertext), ids...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// IsEncrypted reports whether s is an encrypted config value.
+func IsEncrypted(s string) bool {
+	return strings.HasPrefix(s, ENCRYPTED_PREFIX)
+}
+
+// EncryptValue encrypts plaintext with p and returns the encoded config value.
+func EncryptValue(p KeyProvider, plaintext string) (string, error) {
+	sealed, err := p.Encrypt([]byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return ENCRYPTED_PREFIX + p.Algorithm() + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptValue decrypts an encoded config value with the first provider
+// that supports its algorithm and holds the right key.
+func DecryptValue(value string, providers ...KeyProvider) (string, error) {
+	if !IsEncrypted(value) {
+		return "", errors.New("value is not encrypted")
+	}
+	alg, payload, ok := strings.Cut(strings.TrimPrefix(value, ENCRYPTED_PREFIX), ":")
+	if !ok {
+		return "", errors.New("malformed encrypted value")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted value: %w", err)
+	}
+
+	var lastErr error
+	for _, p := range providers {
+		if p.Algorithm() != alg {
+			continue
+		}
+		plain, err := p.Decrypt(sealed)
+		if err == nil {
+			return string(plain), nil
+		}
+		lastErr = err
+	}
+	if lastErr != nil {
+		return "", lastErr
+	}
+	return "", fmt.Errorf("no key provider for %s", alg)
+}
+
+// RotateValues re-encrypts every encrypted value found in content, e.g. the
+// raw bytes of a config file, decrypting with from and encrypting with to.
+// It returns the new content and the number of rotated values.
+func RotateValues(content []byte, to KeyProvider, from ...KeyProvider) ([]byte, int, error) {
+	var (
+		count int
+		err   error
+	)
+	out := encryptedValueRegex.ReplaceAllFunc(content, func(match []byte) []byte {
+		if err != nil {
+			return match
+		}
+		var plain, rotated string
+		if plain, err = DecryptValue(string(match), from...); err != nil {
+			return match
+		}
+		if rotated, err = EncryptValue(to, plain); err != nil {
+			return match
+		}
+		count++
+		return []byte(rotated)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return out, count, nil
+}
+
+// Decrypt registers key providers used to decrypt "enc:v1:" values after the
+// sources are merged. Decrypted values are treated as secrets. Without any
+// provider encrypted values are left untouched.
+func (b *Builder) Decrypt(providers ...KeyProvider) *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.keyProviders = append(b.keyProviders, providers...)
+	return b
+}
+
+func (b *Builder) decryptValues(data map[string]any, parent string) {
+	if len(b.keyProviders) == 0 {
+		return
+	}
+	b.decryptMap(data, parent, parent)
+}
+
+// decryptMap decrypts the values of data. key is the path secrets are
+// recorded under, shown the path with list indexes used in errors.
+func (b *Builder) decryptMap(data map[string]any, key, shown string) {
+	for k, v := range data {
+		childKey, childShown := joinKey(key, k), joinKey(shown, k)
+		switch val := v.(type) {
+		case map[string]any:
+			b.decryptMap(val, childKey, childShown)
+		case []any:
+			b.decryptList(val, childKey, childShown)
+		case string:
+			if !IsEncrypted(val) {
+				continue
+			}
+			if plain := b.decryptKey(childKey, childShown, val); plain != nil {
+				data[k] = plain
+			} else {
+				delete(data, k)
+			}
+		}
+	}
+}
+
+// decryptList decrypts the items of list, recording secrets under key like
+// redact looks them up.
+func (b *Builder) decryptList(list []any, key, shown string) {
+	for i, item := range list {
+		indexed := fmt.Sprintf("%s[%d]", shown, i)
+		switch val := item.(type) {
+		case map[string]any:
+			b.decryptMap(val, key, indexed)
+		case []any:
+			b.decryptList(val, key, indexed)
+		case string:
+			if IsEncrypted(val) {
+				list[i] = b.decryptKey(key, indexed, val)
+			}
+		}
+	}
+}
+
+func (b *Builder) decryptKey(key, shown, value string) any {
+	b.secretPaths[key] = true
+	plain, err := DecryptValue(value, b.keyProviders...)
+	if err != nil {
+		b.errs = append(b.errs, newSecretError(shown, "", err))
+		return nil
+	}
+	return plain
+}
@@ -0,0 +1,168 @@
+package ascanius
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/stretchr/testify/require"
+)
+
+func writeKeyFile(t *testing.T, dir, name string, seed byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	key := bytes.Repeat([]byte{seed}, 32)
+	require.NoError(t, os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0o600))
+	return path
+}
+
+func TestKeyProviders(t *testing.T) {
+	dir := t.TempDir()
+
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	identityPath := filepath.Join(dir, "identity.txt")
+	require.NoError(t, os.WriteFile(identityPath, []byte("# test key\n"+identity.String()+"\n"), 0o600))
+
+	t.Setenv("CONFIG_KEY", strings.Repeat("ab", 32))
+
+	for _, p := range []KeyProvider{
+		NewKeyFileProvider(writeKeyFile(t, dir, "key", 1)),
+		NewEnvKeyProvider("CONFIG_KEY"),
+		NewAgeIdentityProvider(identityPath),
+	} {
+		t.Run(p.Algorithm(), func(t *testing.T) {
+			value, err := EncryptValue(p, "hunter2")
+			require.NoError(t, err)
+			require.True(t, strings.HasPrefix(value, ENCRYPTED_PREFIX+p.Algorithm()+":"))
+
+			plain, err := DecryptValue(value, p)
+			require.NoError(t, err)
+			require.Equal(t, "hunter2", plain)
+		})
+	}
+
+	_, err = DecryptValue("enc:v1:AGE:AAAA", NewKeyFileProvider(filepath.Join(dir, "key")))
+	require.EqualError(t, err, "no key provider for AGE")
+
+	_, err = EncryptValue(NewEnvKeyProvider("MISSING_KEY"), "x")
+	require.Error(t, err)
+}
+
+func TestBuilderDecrypt(t *testing.T) {
+	dir := t.TempDir()
+	key := NewKeyFileProvider(writeKeyFile(t, dir, "key", 1))
+
+	password, err := EncryptValue(key, "s3cr3t")
+	require.NoError(t, err)
+	user, err := EncryptValue(key, "admin")
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("db:\n  user: \""+user+"\"\n  pass: \""+password+"\"\n  host: localhost\n"), 0o644))
+
+	var cfg struct {
+		Db struct {
+			User string
+			Pass string
+			Host string
+		}
+	}
+	builder := New().Source(path, 1).Decrypt(key).Load(&cfg)
+	require.False(t, builder.HasErrs(), builder.Errs())
+	require.Equal(t, "admin", cfg.Db.User)
+	require.Equal(t, "s3cr3t", cfg.Db.Pass)
+
+	var out bytes.Buffer
+	require.NoError(t, builder.Export(&out, "json"))
+	require.NotContains(t, out.String(), "admin")
+	require.NotContains(t, out.String(), "s3cr3t")
+	require.Contains(t, out.String(), "localhost")
+
+	wrongKey := NewKeyFileProvider(writeKeyFile(t, dir, "other", 2))
+	builder = New().Source(path, 1).Decrypt(wrongKey).Load(&cfg)
+	require.Len(t, builder.Errs(), 2)
+	require.Contains(t, builder.Errs()[0].Error(), "cannot decrypt db.")
+}
+
+func TestRotateValues(t *testing.T) {
+	dir := t.TempDir()
+	oldKey := NewKeyFileProvider(writeKeyFile(t, dir, "old", 1))
+	newKey := NewKeyFileProvider(writeKeyFile(t, dir, "new", 2))
+
+	value, err := EncryptValue(oldKey, "s3cr3t")
+	require.NoError(t, err)
+
+	content := []byte("[db]\npassword = \"" + value + "\"\nhost = \"localhost\"\n")
+	rotated, count, err := RotateValues(content, newKey, oldKey)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	require.Contains(t, string(rotated), "host = \"localhost\"")
+
+	rotatedValue := encryptedValueRegex.Find(rotated)
+	plain, err := DecryptValue(string(rotatedValue), newKey)
+	require.NoError(t, err)
+	require.Equal(t, "s3cr3t", plain)
+
+	_, err = DecryptValue(string(rotatedValue), oldKey)
+	require.Error(t, err)
+}
+
+func TestBuilderDecryptLists(t *testing.T) {
+	dir := t.TempDir()
+	key := NewKeyFileProvider(writeKeyFile(t, dir, "key", 1))
+
+	first, err := EncryptValue(key, "pass-a")
+	require.NoError(t, err)
+	second, err := EncryptValue(key, "pass-b")
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`servers:
+  - host: a
+    password: "`+first+`"
+  - host: b
+    password: "`+second+`"
+tokens:
+  - ["`+first+`"]
+`), 0o644))
+
+	var cfg struct {
+		Servers []struct {
+			Host     string
+			Password string
+		}
+		Tokens [][]string
+	}
+	builder := New().Source(path, 1).Decrypt(key).Load(&cfg)
+	require.False(t, builder.HasErrs(), builder.Errs())
+	require.Len(t, cfg.Servers, 2)
+	require.Equal(t, "pass-a", cfg.Servers[0].Password)
+	require.Equal(t, "pass-b", cfg.Servers[1].Password)
+	require.Equal(t, [][]string{{"pass-a"}}, cfg.Tokens)
+
+	var out bytes.Buffer
+	require.NoError(t, builder.Export(&out, "json"))
+	require.NotContains(t, out.String(), "pass-a")
+	require.NotContains(t, out.String(), "pass-b")
+	require.Contains(t, out.String(), `"host": "b"`)
+
+	wrongKey := NewKeyFileProvider(writeKeyFile(t, dir, "other", 2))
+	builder = New().Source(path, 1).Decrypt(wrongKey).Load(&cfg)
+	var msgs []string
+	for _, err := range builder.Errs() {
+		msgs = append(msgs, err.Error())
+		var serr *SecretError
+		require.ErrorAs(t, err, &serr)
+		require.Empty(t, serr.Ref)
+		if serr.Key == "servers[1].password" {
+			require.Equal(t, 1, serr.Index)
+		}
+	}
+	require.Contains(t, strings.Join(msgs, "\n"), "cannot decrypt servers[1].password")
+	require.Contains(t, strings.Join(msgs, "\n"), "cannot decrypt tokens[0][0]")
+}
@@ -1,8 +1,12 @@
 package ascanius
 
 import (
-	"encoding/json"
+	"bytes"
+	"errors"
+	"fmt"
 	"os"
+	"reflect"
+	"regexp"
 	"strings"
 
 	"github.com/joho/godotenv"
@@ -42,6 +46,7 @@ type EnvSource struct {
 	priority int
 	prefix   string
 	sep      string
+	state    *fileState
 }
 
 func NewEnvSource(name string, priority int, opts ...func(*EnvSource)) *EnvSource {
@@ -50,6 +55,7 @@ func NewEnvSource(name string, priority int, opts ...func(*EnvSource)) *EnvSourc
 		priority: priority,
 		prefix:   DEFAULT_ENV_PREFIX,
 		sep:      DEFAULT_ENV_SEPARATOR,
+		state:    &fileState{},
 	}
 	for _, opt := range opts {
 		opt(e)
@@ -86,10 +92,15 @@ func (e *EnvSource) Load() (map[string]any, error) {
 			}
 		}
 	} else {
-		envMap, err := godotenv.Read(e.name)
+		raw, err := os.ReadFile(e.name)
 		if err != nil {
 			return nil, err
 		}
+		envMap, err := godotenv.UnmarshalBytes(raw)
+		if err != nil {
+			return nil, newParseError(e.name, raw, err)
+		}
+		e.state.loaded(raw)
 		for k, v := range envMap {
 			if strings.HasPrefix(k, prefix) {
 				key := strings.TrimPrefix(k, prefix)
@@ -110,12 +121,7 @@ func expandEnv(flat map[string]string, sep string) map[string]any {
 
 		for i, part := range parts {
 			if i == len(parts)-1 {
-				var val any
-				if err := json.Unmarshal([]byte(raw), &val); err == nil {
-					current[part] = val
-				} else {
-					current[part] = raw
-				}
+				current[part] = inferValue(raw)
 			} else {
 				if _, ok := current[part]; !ok {
 					current[part] = map[string]any{}
@@ -127,3 +133,111 @@ func expandEnv(flat map[string]string, sep string) map[string]any {
 
 	return root
 }
+
+// inferValue parses raw as a JSON literal (number, bool, array...) and falls
+// back to the raw string when it is not valid JSON. Integers are int64.
+func inferValue(raw string) any {
+	var val any
+	if err := unmarshalJson([]byte(raw), &val); err == nil {
+		return val
+	}
+	return raw
+}
+
+// Set records a new value for key in the .env file, written by Save. The
+// process environment is not writable.
+func (e *EnvSource) Set(key string, value any) error {
+	if e.name == ENV {
+		return errors.New("the process environment is not writable")
+	}
+	return e.state.set(e.name, key, value)
+}
+
+// Save writes the values recorded by Set. Existing variables are edited in
+// place and new ones appended, keeping comments and order.
+func (e *EnvSource) Save() error {
+	if e.name == ENV {
+		return nil
+	}
+	return e.state.save(e.name, func(content []byte, edit fileEdit) ([]byte, error) {
+		return editDotenv(content, edit, e.prefix, e.sep)
+	})
+}
+
+func (e *EnvSource) hasPending() bool {
+	return e.state.hasPending()
+}
+
+var dotenvLineRegex = regexp.MustCompile(`^(\s*(?:export\s+)?)([A-Za-z_][A-Za-z0-9_.]*)\s*=`)
+
+// editDotenv sets the variable holding e, named like Load reads it.
+func editDotenv(content []byte, e fileEdit, prefix, sep string) ([]byte, error) {
+	varPrefix := prefix + sep
+	suffix := strings.ReplaceAll(e.key, ".", sep)
+	value, err := formatDotenvValue(e.value)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	replaced := false
+	for i, line := range lines {
+		m := dotenvLineRegex.FindSubmatchIndex(line)
+		if m == nil {
+			continue
+		}
+		name := string(line[m[4]:m[5]])
+		if !strings.HasPrefix(name, varPrefix) || strings.ToLower(strings.TrimPrefix(name, varPrefix)) != suffix {
+			continue
+		}
+		if !dotenvSingleLine(bytes.TrimSpace(line[m[1]:])) {
+			return nil, fmt.Errorf("%s spans several lines", name)
+		}
+		eol := ""
+		if bytes.HasSuffix(line, []byte("\n")) {
+			eol = "\n"
+		}
+		lines[i] = []byte(string(line[:m[1]]) + value + eol)
+		replaced = true
+	}
+
+	edited := bytes.Join(lines, nil)
+	if !replaced {
+		if len(edited) > 0 && !bytes.HasSuffix(edited, []byte("\n")) {
+			edited = append(edited, '\n')
+		}
+		edited = append(edited, varPrefix+strings.ToUpper(suffix)+"="+value+"\n"...)
+	}
+
+	// make sure the file reads back the value that was set
+	check, err := godotenv.UnmarshalBytes(edited)
+	if err != nil {
+		return nil, fmt.Errorf("the edit would make the file invalid: %w", err)
+	}
+	for name, raw := range check {
+		if strings.HasPrefix(name, varPrefix) && strings.ToLower(strings.TrimPrefix(name, varPrefix)) == suffix {
+			if got := inferValue(raw); e.value != nil && !reflect.DeepEqual(got, e.value) && fmt.Sprint(got) != fmt.Sprint(e.value) {
+				return nil, fmt.Errorf("the edit would read back as %v", got)
+			}
+		}
+	}
+	return edited, nil
+}
+
+// dotenvSingleLine reports whether the value starting a line, quoted or
+// not, ends on that line.
+func dotenvSingleLine(value []byte) bool {
+	if len(value) == 0 || (value[0] != '"' && value[0] != '\'') {
+		return true
+	}
+	for i := 1; i < len(value); i++ {
+		if value[i] == '\\' && value[0] == '"' {
+			i++
+			continue
+		}
+		if value[i] == value[0] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,319 @@
+package ascanius
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// SourceError reports a source that could not be loaded.
+type SourceError struct {
+	Source string
+	Err    error
+}
+
+func (e *SourceError) Error() string {
+	// parse errors already name the file
+	var perr *ParseError
+	if errors.As(e.Err, &perr) && perr.Path == e.Source {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("source %s: %v", e.Source, e.Err)
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// ParseError reports a document that is not valid in its format. Line and
+// Column are 1-based and zero when the parser does not report them. Snippet
+// shows the offending line with a caret under the column.
+type ParseError struct {
+	Path    string
+	Line    int
+	Column  int
+	Snippet string
+	Err     error
+}
+
+func (e *ParseError) Error() string {
+	var msg string
+	switch {
+	case e.Line == 0:
+		msg = fmt.Sprintf("%s: %v", e.Path, e.Err)
+	case e.Column == 0:
+		msg = fmt.Sprintf("%s:%d: %v", e.Path, e.Line, e.Err)
+	default:
+		msg = fmt.Sprintf("%s:%d:%d: %v", e.Path, e.Line, e.Column, e.Err)
+	}
+	if e.Snippet != "" {
+		msg += "\n" + e.Snippet
+	}
+	return msg
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+var yamlLineRegex = regexp.MustCompile(`\bline (\d+):`)
+
+// newParseError locates err in data, the document that failed to parse.
+func newParseError(path string, data []byte, err error) *ParseError {
+	perr := &ParseError{Path: path, Err: err}
+
+	var (
+		derr   *toml.DecodeError
+		serr   *json.SyntaxError
+		j5err  *syntaxError
+		lerr   *lineError
+		terr   *json.UnmarshalTypeError
+		yerr   *yaml.TypeError
+		offset int64 = -1
+	)
+	switch {
+	case errors.As(err, &derr):
+		perr.Line, perr.Column = derr.Position()
+	case errors.As(err, &serr):
+		offset = serr.Offset
+	case errors.As(err, &terr):
+		offset = terr.Offset
+	case errors.As(err, &j5err):
+		offset = j5err.offset
+	case errors.As(err, &lerr):
+		perr.Line = lerr.line
+	case errors.As(err, &yerr) && len(yerr.Errors) > 0:
+		perr.Line = yamlLine(yerr.Errors[0])
+	default:
+		perr.Line = yamlLine(err.Error())
+	}
+	if offset >= 0 {
+		perr.Line, perr.Column = offsetPosition(data, offset)
+	}
+
+	perr.Snippet = snippet(data, perr.Line, perr.Column)
+	return perr
+}
+
+func yamlLine(msg string) int {
+	if m := yamlLineRegex.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return line
+	}
+	return 0
+}
+
+// offsetPosition converts a byte offset to a 1-based line and column. The
+// JSON decoder reports the offset just past the offending byte.
+func offsetPosition(data []byte, offset int64) (int, int) {
+	offset = min(offset, int64(len(data)))
+	prefix := data[:offset]
+	line := bytes.Count(prefix, []byte("\n")) + 1
+	column := len(prefix) - bytes.LastIndexByte(prefix, '\n') - 1
+	return line, max(column, 1)
+}
+
+// snippet returns the line before line, line itself and a caret under
+// column, each prefixed with its line number.
+func snippet(data []byte, line, column int) string {
+	lines := strings.Split(string(data), "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+
+	var sb strings.Builder
+	width := len(strconv.Itoa(line))
+	for n := max(line-1, 1); n <= line; n++ {
+		fmt.Fprintf(&sb, "%*d | %s\n", width, n, strings.TrimRight(lines[n-1], "\r"))
+	}
+	if column > 0 {
+		// keep tabs so the caret lines up with the text above
+		var pad strings.Builder
+		for i, r := range lines[line-1] {
+			if i >= column-1 {
+				break
+			}
+			if r == '\t' {
+				pad.WriteRune('\t')
+			} else {
+				pad.WriteRune(' ')
+			}
+		}
+		fmt.Fprintf(&sb, "%*s | %s^\n", width, "", pad.String())
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// BindError reports a merged value that cannot be stored in the target
+// field. Key is the dotted key path, empty when the target itself is invalid.
+type BindError struct {
+	Key  string
+	Type reflect.Type
+	Err  error
+}
+
+func (e *BindError) Error() string {
+	if e.Key == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("cannot bind %s to %s: %v", e.Key, e.Type, e.Err)
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+// SecretError reports a secret reference that cannot be resolved, or an
+// encrypted value that cannot be decrypted. Key is the dotted key path with
+// list indexes, e.g. servers[1].password, and Index the position of the
+// value in the innermost list around it, or -1.
+type SecretError struct {
+	Key   string
+	Index int
+	// Ref is the reference, empty for encrypted values
+	Ref string
+	Err error
+}
+
+func newSecretError(key, ref string, err error) *SecretError {
+	index := -1
+	open, end := strings.LastIndexByte(key, '['), strings.LastIndexByte(key, ']')
+	if open >= 0 && end > open {
+		if n, convErr := strconv.Atoi(key[open+1 : end]); convErr == nil {
+			index = n
+		}
+	}
+	return &SecretError{Key: key, Index: index, Ref: ref, Err: err}
+}
+
+func (e *SecretError) Error() string {
+	if e.Ref == "" {
+		return fmt.Sprintf("cannot decrypt %s: %v", e.Key, e.Err)
+	}
+	return fmt.Sprintf("cannot resolve %s (%s): %v", e.Key, e.Ref, e.Err)
+}
+
+func (e *SecretError) Unwrap() error {
+	return e.Err
+}
+
+// SectionNotFoundError is reported by LoadSection when the merged
+// configuration has no section with the requested name.
+type SectionNotFoundError struct {
+	Section string
+}
+
+func (e *SectionNotFoundError) Error() string {
+	return fmt.Sprintf("section '%s' not found", e.Section)
+}
+
+// Err returns the errors of the last load joined with errors.Join, or nil.
+func (b *Builder) Err() error {
+	return errors.Join(b.Errs()...)
+}
+
+// panicError is the value passed to panic by Builder.Panic.
+type panicError struct {
+	errs []error
+}
+
+func (e *panicError) Error() string {
+	return errorReport(e.errs)
+}
+
+func (e *panicError) Unwrap() []error {
+	return e.errs
+}
+
+// errorReport formats errs as a multi-line report grouped by the source
+// they come from.
+func errorReport(errs []error) string {
+	groups := make(map[string][]string)
+	var order []string
+	for _, err := range errs {
+		group := errorGroup(err)
+		if _, ok := groups[group]; !ok {
+			order = append(order, group)
+		}
+		groups[group] = append(groups[group], err.Error())
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		// general and binding errors come after the sources
+		return groupRank(order[i]) < groupRank(order[j])
+	})
+
+	var sb strings.Builder
+	if len(errs) == 1 {
+		sb.WriteString("configuration error:\n")
+	} else {
+		fmt.Fprintf(&sb, "%d configuration errors:\n", len(errs))
+	}
+	for _, group := range order {
+		fmt.Fprintf(&sb, "  %s:\n", group)
+		for _, msg := range groups[group] {
+			lines := strings.Split(msg, "\n")
+			fmt.Fprintf(&sb, "    - %s\n", lines[0])
+			for _, line := range lines[1:] {
+				fmt.Fprintf(&sb, "      %s\n", line)
+			}
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+const (
+	mergedGroup  = "merged configuration"
+	bindingGroup = "binding"
+	generalGroup = "general"
+)
+
+func errorGroup(err error) string {
+	var (
+		serr *SourceError
+		perr *ParseError
+		verr *ValidationError
+		berr *BindError
+		nerr *SectionNotFoundError
+		aerr *AliasConflictError
+		xerr *SecretError
+	)
+	switch {
+	case errors.As(err, &serr):
+		return serr.Source
+	case errors.As(err, &perr):
+		return perr.Path
+	case errors.As(err, &verr):
+		if verr.Source == "" {
+			return mergedGroup
+		}
+		return verr.Source
+	case errors.As(err, &aerr), errors.As(err, &xerr):
+		return mergedGroup
+	case errors.As(err, &berr), errors.As(err, &nerr):
+		return bindingGroup
+	default:
+		return generalGroup
+	}
+}
+
+func groupRank(group string) int {
+	switch group {
+	case mergedGroup:
+		return 1
+	case bindingGroup:
+		return 2
+	case generalGroup:
+		return 3
+	default:
+		return 0
+	}
+}
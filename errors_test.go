@@ -0,0 +1,166 @@
+package ascanius
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypedErrors(t *testing.T) {
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.toml")
+	missing := filepath.Join(dir, "missing.json")
+	require.NoError(t, os.WriteFile(bad, []byte("port = 80\nhost = \n"), 0o600))
+
+	var cfg struct {
+		Port int
+		Tls  struct{ Enabled bool }
+	}
+	b := New().
+		Source(bad, 1).
+		Source(missing, 2).
+		AddSource(&stubSource{name: "overrides", priority: 3, data: map[string]any{"port": "eighty"}}).
+		LoadSection(&cfg, "Database")
+
+	errs := b.Errs()
+	require.Len(t, errs, 3)
+
+	var perr *ParseError
+	require.ErrorAs(t, errs[0], &perr)
+	assert.Equal(t, bad, perr.Path)
+	assert.Equal(t, 2, perr.Line)
+	assert.Positive(t, perr.Column)
+
+	var serr *SourceError
+	require.ErrorAs(t, errs[1], &serr)
+	assert.Equal(t, missing, serr.Source)
+	assert.ErrorIs(t, errs[1], os.ErrNotExist)
+
+	var nerr *SectionNotFoundError
+	require.ErrorAs(t, errs[2], &nerr)
+	assert.Equal(t, "database", nerr.Section)
+
+	joined := b.Err()
+	require.Error(t, joined)
+	assert.ErrorIs(t, joined, os.ErrNotExist)
+	assert.ErrorAs(t, joined, &nerr)
+}
+
+func TestBindError(t *testing.T) {
+	var cfg struct {
+		Port int
+		Host string
+	}
+	b := New().
+		AddSource(&stubSource{name: "overrides", priority: 1, data: map[string]any{"port": "eighty", "host": "db"}}).
+		Load(&cfg)
+
+	var berr *BindError
+	require.Len(t, b.Errs(), 1)
+	require.ErrorAs(t, b.Errs()[0], &berr)
+	assert.Equal(t, "port", berr.Key)
+	assert.Equal(t, reflect.TypeOf(0), berr.Type)
+	assert.Equal(t, "db", cfg.Host)
+
+	assert.NoError(t, New().Err())
+}
+
+func TestPanicReport(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.json")
+	b := New().
+		Source(missing, 1).
+		Source("config.xml", 2).
+		AddSource(&stubSource{name: "overrides", priority: 3, data: map[string]any{"port": "eighty"}}).
+		Load(&struct{ Port int }{})
+
+	defer func() {
+		err, ok := recover().(error)
+		require.True(t, ok)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+
+		report := err.Error()
+		assert.Regexp(t, `^3 configuration errors:\n`, report)
+		assert.Contains(t, report, "  "+missing+":\n    - source "+missing+": open ")
+		assert.Contains(t, report, "  binding:\n    - cannot bind port to int: ")
+		assert.Contains(t, report, "  general:\n    - unsupported source type for config.xml")
+		assert.Less(t, strings.Index(report, "binding:"), strings.Index(report, "general:"))
+	}()
+	b.Panic()
+	t.Fatal("Panic did not panic")
+}
+
+func TestSourceErrorMessage(t *testing.T) {
+	perr := &ParseError{Path: "config.json", Line: 3, Column: 7, Err: errors.New("invalid character")}
+	assert.Equal(t, "config.json:3:7: invalid character", (&SourceError{Source: "config.json", Err: perr}).Error())
+	assert.Equal(t, "source base: config.json:3:7: invalid character", (&SourceError{Source: "base", Err: perr}).Error())
+}
+
+func TestParseErrorPosition(t *testing.T) {
+	tests := []struct {
+		file    string
+		content string
+		line    int
+		column  int
+		snippet string
+	}{
+		{
+			file:    "config.json",
+			content: "{\n  \"host\": \"db\",\n  \"port\": }\n",
+			line:    3, column: 11,
+			snippet: "2 |   \"host\": \"db\",\n3 |   \"port\": }\n  |           ^",
+		},
+		{
+			file:    "trailing.json",
+			content: "{\"host\": \"db\"}\n{}\n",
+			line:    2, column: 1,
+			snippet: "1 | {\"host\": \"db\"}\n2 | {}\n  | ^",
+		},
+		{
+			file:    "config.toml",
+			content: "host = \"db\"\nport = \n",
+			line:    2, column: 8,
+			snippet: "1 | host = \"db\"\n2 | port = \n  |        ^",
+		},
+		{
+			file:    "config.yaml",
+			content: "host: db\n  port: 80\n",
+			line:    2,
+			snippet: "1 | host: db\n2 |   port: 80",
+		},
+		{
+			file:    ".env",
+			content: "APP_HOST=db\nAPP-PORT=80\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+
+			b := New().Source(path, 1).Load(&struct{}{})
+			require.Len(t, b.Errs(), 1)
+
+			var perr *ParseError
+			require.ErrorAs(t, b.Errs()[0], &perr)
+			assert.Equal(t, path, perr.Path)
+			assert.Equal(t, tt.line, perr.Line)
+			assert.Equal(t, tt.column, perr.Column)
+			if tt.snippet != "" {
+				assert.Equal(t, tt.snippet, perr.Snippet)
+				assert.True(t, strings.HasSuffix(b.Errs()[0].Error(), "\n"+tt.snippet))
+			}
+		})
+	}
+}
+
+func TestSnippetTabs(t *testing.T) {
+	assert.Equal(t, "1 | \tkey = }\n  | \t      ^", snippet([]byte("\tkey = }"), 1, 8))
+	assert.Empty(t, snippet([]byte("a = 1"), 3, 1))
+}
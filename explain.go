@@ -0,0 +1,91 @@
+package ascanius
+
+import "strings"
+
+// Provenance describes the value a single source holds for a key.
+type Provenance struct {
+	Source    string
+	Priority  int
+	Value     any  // secret values are masked
+	Effective bool // true for the highest priority source defining the key
+}
+
+// Lookup returns the merged value stored at a dotted key path such as
+// "mongo.replicaSet". Key segments are normalized to snake_case like
+// source keys are.
+func (b *Builder) Lookup(key string) (any, bool) {
+	b.mu.Lock()
+	defer b.unlock()
+	return lookupPath(b.current(), normalizeKeyPath(key))
+}
+
+// Explain lists, in priority order, every loaded source that defines key
+// together with the value it provides.
+func (b *Builder) Explain(key string) []Provenance {
+	b.mu.Lock()
+	defer b.unlock()
+
+	b.current()
+	key = normalizeKeyPath(key)
+
+	var out []Provenance
+	for _, src := range b.sortedSources() {
+		data, ok := b.mapSource[src.Name()]
+		if !ok {
+			continue
+		}
+		value, ok := lookupPath(data, key)
+		if !ok {
+			continue
+		}
+		out = append(out, Provenance{
+			Source:   src.Name(),
+			Priority: src.Priority(),
+			Value:    b.redactKey(key, value),
+		})
+	}
+	if len(out) > 0 {
+		out[len(out)-1].Effective = true
+	}
+	return out
+}
+
+// IsSecret reports whether the value at key is masked in dumps.
+func (b *Builder) IsSecret(key string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.isSecretKey(normalizeKeyPath(key), nil)
+}
+
+func (b *Builder) redactKey(key string, value any) any {
+	if b.isSecretKey(key, nil) && value != nil {
+		return SECRET_MASK
+	}
+	return b.redactValue(deepCopyValue(value), key, nil)
+}
+
+func normalizeKeyPath(key string) string {
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		parts[i] = toSnakeCase(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+func lookupPath(data map[string]any, key string) (any, bool) {
+	if key == "" {
+		return data, true
+	}
+	var current any = data
+	for _, part := range strings.Split(key, ".") {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		current, ok = m[part]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
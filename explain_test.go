@@ -0,0 +1,35 @@
+package ascanius
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupAndExplain(t *testing.T) {
+	t.Setenv("APP__MONGO__PASSWORD", "env-pass")
+
+	builder := New().
+		Source("env", 100).
+		Source("./files/config.toml", 1).
+		Source("./files/mongo.json", 2)
+
+	value, ok := builder.Lookup("mongo.replicaSet")
+	require.True(t, ok)
+	require.Equal(t, "rs0", value)
+
+	_, ok = builder.Lookup("mongo.missing")
+	require.False(t, ok)
+
+	origins := builder.Explain("Mongo.Password")
+	require.Equal(t, []Provenance{
+		{Source: "./files/config.toml", Priority: 1, Value: SECRET_MASK},
+		{Source: "./files/mongo.json", Priority: 2, Value: SECRET_MASK},
+		{Source: "env", Priority: 100, Value: SECRET_MASK, Effective: true},
+	}, origins)
+
+	origins = builder.Explain("mongo.port")
+	require.Len(t, origins, 2)
+	require.Equal(t, int64(27018), origins[1].Value)
+	require.True(t, origins[1].Effective)
+}
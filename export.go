@@ -0,0 +1,286 @@
+package ascanius
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+const dotenvSpecialChars = "\\\n\r\"!$`"
+
+// Merged returns a copy of the configuration produced by merging every source.
+// If nothing has been loaded yet the sources are loaded and merged first.
+func (b *Builder) Merged() map[string]any {
+	b.mu.Lock()
+	defer b.unlock()
+	return deepCopyMap(b.current())
+}
+
+// Export writes the merged configuration to w using the given format
+// ("json", "yaml", "toml" or ".env"). Secret values are masked unless
+// RevealSecrets was called.
+func (b *Builder) Export(w io.Writer, format string) error {
+	b.mu.Lock()
+	defer b.unlock()
+
+	data := deepCopyMap(b.current())
+	if !b.revealSecrets {
+		data = b.redact(data, "", nil)
+	}
+	return b.encode(w, format, data)
+}
+
+// ExportStruct writes a bound config struct to w using the given format.
+// Keys are resolved the same way Load resolves them, so the output can be
+// loaded back into the same struct.
+func (b *Builder) ExportStruct(w io.Writer, format string, target any) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	secrets := make(map[string]bool)
+	data, err := structToMap(target, secrets, "")
+	if err != nil {
+		return err
+	}
+	if !b.revealSecrets {
+		data = b.redact(data, "", secrets)
+	}
+	return b.encode(w, format, data)
+}
+
+func (b *Builder) encode(w io.Writer, format string, data map[string]any) error {
+	out, err := encodeFormat(format, data, b.envPrefix, b.envSep)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// encodeFormat encodes data as format. prefix and sep are used for .env.
+func encodeFormat(format string, data map[string]any, prefix, sep string) ([]byte, error) {
+	switch normalizeFormat(format) {
+	case JSON_SOURCE_NAME:
+		return marshalJson(data)
+	case YAML_SOURCE_NAME:
+		return yaml.Marshal(data)
+	case TOML_SOURCE_NAME:
+		return toml.Marshal(dropNils(data))
+	case DOTENV_SOURCE_NAME:
+		return marshalDotenv(data, prefix, sep)
+	default:
+		return nil, fmt.Errorf("unsupported export format %s", format)
+	}
+}
+
+func marshalJson(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(jsonFloats(v)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// jsonFloats replaces the NaN and infinite floats JSON5 sources can hold,
+// which JSON cannot represent, with the strings "NaN", "Infinity" and
+// "-Infinity". Binding parses them back into float fields.
+func jsonFloats(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			out[k] = jsonFloats(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = jsonFloats(item)
+		}
+		return out
+	case float64:
+		switch {
+		case math.IsNaN(val):
+			return "NaN"
+		case math.IsInf(val, 1):
+			return "Infinity"
+		case math.IsInf(val, -1):
+			return "-Infinity"
+		}
+	}
+	return v
+}
+
+func normalizeFormat(format string) string {
+	switch strings.TrimPrefix(strings.ToLower(format), ".") {
+	case "json":
+		return JSON_SOURCE_NAME
+	case "yaml", "yml":
+		return YAML_SOURCE_NAME
+	case "toml":
+		return TOML_SOURCE_NAME
+	case "env", "dotenv":
+		return DOTENV_SOURCE_NAME
+	default:
+		return ""
+	}
+}
+
+// structToMap converts a config struct to a map keyed like Load would read it.
+// The key paths of secret fields are recorded in secrets when it is not nil.
+func structToMap(target any, secrets map[string]bool, parent string) (map[string]any, error) {
+	val := reflect.ValueOf(target)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil, errors.New("target must be a non-nil struct or pointer to a struct")
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return nil, errors.New("target must be a struct or pointer to a struct")
+	}
+
+	out := make(map[string]any)
+	typ := val.Type()
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		key := field.Tag.Get("cfg")
+		if key == "" {
+			key = toSnakeCase(field.Name)
+		}
+
+		fieldVal := val.Field(i)
+		if isSecretField(field) {
+			if secrets != nil {
+				secrets[joinKey(parent, key)] = true
+			}
+			if reader, ok := fieldVal.Interface().(secretReader); ok {
+				out[key] = reader.secretRaw()
+				continue
+			}
+		}
+
+		if fieldVal.Kind() == reflect.Ptr && fieldVal.Type().Elem().Kind() == reflect.Struct {
+			if fieldVal.IsNil() {
+				continue
+			}
+			fieldVal = fieldVal.Elem()
+		}
+
+		if fieldVal.Kind() == reflect.Struct {
+			sub, err := structToMap(fieldVal.Interface(), secrets, joinKey(parent, key))
+			if err != nil {
+				return nil, fmt.Errorf("error in section %s: %w", key, err)
+			}
+			out[key] = sub
+			continue
+		}
+
+		out[key] = fieldVal.Interface()
+	}
+	return out, nil
+}
+
+func dropNils(data map[string]any) map[string]any {
+	out := make(map[string]any, len(data))
+	for k, v := range data {
+		switch val := v.(type) {
+		case nil:
+			continue
+		case map[string]any:
+			out[k] = dropNils(val)
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// marshalDotenv writes data as variables named like EnvSource reads them:
+// prefix and sep come first, even when prefix is empty.
+func marshalDotenv(data map[string]any, prefix, sep string) ([]byte, error) {
+	flat := make(map[string]any)
+	flattenMap(flat, data, "", sep)
+
+	keys := make([]string, 0, len(flat))
+	for k := range flat {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	for _, k := range keys {
+		value, err := formatDotenvValue(flat[k])
+		if err != nil {
+			return nil, fmt.Errorf("cannot export %s: %w", k, err)
+		}
+		fmt.Fprintf(&buf, "%s%s%s=%s\n", prefix, sep, strings.ToUpper(k), value)
+	}
+	return buf.Bytes(), nil
+}
+
+func flattenMap(dst map[string]any, src map[string]any, parent, sep string) {
+	for k, v := range src {
+		key := k
+		if parent != "" {
+			key = parent + sep + k
+		}
+		if sub, ok := v.(map[string]any); ok {
+			flattenMap(dst, sub, key, sep)
+			continue
+		}
+		dst[key] = v
+	}
+}
+
+// formatDotenvValue quotes a value so that godotenv and expandEnv read back
+// the same value and type: strings that would otherwise be parsed as JSON
+// literals are kept as JSON strings, everything else that is not a string
+// is written as JSON.
+func formatDotenvValue(v any) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+
+	if s, ok := v.(string); ok && !json.Valid([]byte(s)) {
+		return `"` + dotenvEscape(s) + `"`, nil
+	}
+
+	raw, err := json.Marshal(jsonFloats(v))
+	if err != nil {
+		return "", err
+	}
+	if !strings.ContainsAny(string(raw), "'\n") {
+		return "'" + string(raw) + "'", nil
+	}
+	return `"` + dotenvEscape(string(raw)) + `"`, nil
+}
+
+func dotenvEscape(s string) string {
+	for _, c := range dotenvSpecialChars {
+		replacement := "\\" + string(c)
+		switch c {
+		case '\n':
+			replacement = `\n`
+		case '\r':
+			replacement = `\r`
+		}
+		s = strings.ReplaceAll(s, string(c), replacement)
+	}
+	return s
+}
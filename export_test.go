@@ -0,0 +1,88 @@
+package ascanius
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportRoundTrip(t *testing.T) {
+	type A struct {
+		Mongo MongoConfig `cfg:"mongo"`
+	}
+
+	var want A
+	builder := New().Source("./files/mongo.yaml", 100).Load(&want)
+	require.False(t, builder.HasErrs())
+	want.Mongo.Password = SECRET_MASK
+
+	for _, ext := range []string{".json", ".yaml", ".toml", ".env"} {
+		t.Run(ext, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, builder.Export(&buf, ext))
+
+			path := filepath.Join(t.TempDir(), "config"+ext)
+			if ext == ".env" {
+				path = filepath.Join(t.TempDir(), ".env.export")
+			}
+			require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+
+			var got A
+			reloaded := New().Source(path, 1).Load(&got)
+			require.False(t, reloaded.HasErrs(), reloaded.Errs())
+			require.Equal(t, want, got)
+		})
+	}
+}
+
+func TestExportDotenvEmptyPrefix(t *testing.T) {
+	builder := New().EnvPrefix("").AddSource(&stubSource{name: "stub", priority: 1, data: map[string]any{
+		"mongo": map[string]any{"host": "db", "port": int64(27017)},
+	}})
+
+	var buf bytes.Buffer
+	require.NoError(t, builder.Export(&buf, "env"))
+	require.Equal(t, "__MONGO__HOST=\"db\"\n__MONGO__PORT='27017'\n", buf.String())
+
+	path := filepath.Join(t.TempDir(), ".env")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+	data, err := NewEnvSource(path, 1, WithPrefix("")).Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"mongo": map[string]any{"host": "db", "port": int64(27017)}}, data)
+}
+
+func TestExportStruct(t *testing.T) {
+	type Server struct {
+		Host string `cfg:"address"`
+		Port uint16
+	}
+	type Config struct {
+		Server Server
+		Tags   []string
+		Name   string
+	}
+
+	cfg := Config{
+		Server: Server{Host: "127.0.0.1", Port: 8080},
+		Tags:   []string{"a", "b"},
+		Name:   "true",
+	}
+
+	var buf bytes.Buffer
+	builder := New()
+	require.NoError(t, builder.ExportStruct(&buf, "env", &cfg))
+	require.Equal(t, "APP__NAME='\"true\"'\nAPP__SERVER__ADDRESS=\"127.0.0.1\"\nAPP__SERVER__PORT='8080'\nAPP__TAGS='[\"a\",\"b\"]'\n", buf.String())
+
+	path := filepath.Join(t.TempDir(), ".env")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+
+	var got Config
+	reloaded := New().Source(path, 1).Load(&got)
+	require.False(t, reloaded.HasErrs(), reloaded.Errs())
+	require.Equal(t, cfg, got)
+
+	require.Error(t, builder.ExportStruct(&buf, "xml", &cfg))
+}
@@ -0,0 +1,285 @@
+package ascanius
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adrenaissance/ascanius/internal/atomicfile"
+)
+
+const (
+	HTTP_SOURCE_NAME     = "http"
+	DEFAULT_HTTP_TIMEOUT = 10 * time.Second
+	// DEFAULT_HTTP_POLL_INTERVAL is used by Poll when the interval is not
+	// positive
+	DEFAULT_HTTP_POLL_INTERVAL = time.Minute
+)
+
+// HttpSource loads a JSON, YAML or TOML document served over HTTP(S).
+// Requests are conditional (ETag / If-Modified-Since) once a document has
+// been fetched, and the last known good document can be kept on disk to be
+// used while the server is unreachable.
+type HttpSource struct {
+	name      string
+	url       string
+	priority  int
+	format    string
+	profile   string
+	headers   http.Header
+	timeout   time.Duration
+	tlsConfig *tls.Config
+	cacheFile string
+	client    *http.Client
+
+	mu           sync.Mutex
+	last         map[string]any
+	etag         string
+	lastModified string
+}
+
+type httpCache struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	Format       string `json:"format"`
+	Body         string `json:"body"`
+}
+
+func NewHttpSource(url string, name string, priority int, opts ...func(*HttpSource)) *HttpSource {
+	if name == "" {
+		name = url
+	}
+	s := &HttpSource{
+		name:     name,
+		url:      url,
+		priority: priority,
+		headers:  make(http.Header),
+		timeout:  DEFAULT_HTTP_TIMEOUT,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	// keep the proxy, dial and idle connection defaults
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if s.tlsConfig != nil {
+		transport.TLSClientConfig = s.tlsConfig
+	}
+	s.client = &http.Client{Timeout: s.timeout, Transport: transport}
+	return s
+}
+
+// WithHttpFormat forces the document format ("json", "yaml" or "toml")
+// instead of detecting it from the Content-Type header or URL extension.
+func WithHttpFormat(format string) func(*HttpSource) {
+	return func(s *HttpSource) {
+		s.format = normalizeFormat(format)
+	}
+}
+
+// WithHttpProfile selects the YAML documents of profile, like
+// WithYamlProfile.
+func WithHttpProfile(profile string) func(*HttpSource) {
+	return func(s *HttpSource) {
+		s.profile = profile
+	}
+}
+
+func WithHttpHeader(key, value string) func(*HttpSource) {
+	return func(s *HttpSource) {
+		s.headers.Add(key, value)
+	}
+}
+
+func WithHttpTLS(config *tls.Config) func(*HttpSource) {
+	return func(s *HttpSource) {
+		s.tlsConfig = config
+	}
+}
+
+func WithHttpTimeout(timeout time.Duration) func(*HttpSource) {
+	return func(s *HttpSource) {
+		s.timeout = timeout
+	}
+}
+
+// WithHttpCache keeps the last successfully fetched document in path.
+func WithHttpCache(path string) func(*HttpSource) {
+	return func(s *HttpSource) {
+		s.cacheFile = path
+	}
+}
+
+func (s *HttpSource) Name() string        { return s.name }
+func (s *HttpSource) SetName(name string) { s.name = name }
+func (s *HttpSource) Priority() int       { return s.priority }
+func (s *HttpSource) SetPriority(p int)   { s.priority = p }
+func (s *HttpSource) Type() string        { return HTTP_SOURCE_NAME }
+
+// Load fetches the document. When the server cannot be reached or answers
+// with an error, the last known good document (from memory or the cache
+// file) is returned together with the error.
+func (s *HttpSource) Load() (map[string]any, error) {
+	return s.LoadContext(context.Background())
+}
+
+// LoadContext is Load with the request bound to ctx.
+func (s *HttpSource) LoadContext(ctx context.Context) (map[string]any, error) {
+	data, _, err := s.fetch(ctx)
+	if err == nil || data != nil {
+		return data, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.last != nil {
+		return deepCopyMap(s.last), fmt.Errorf("%w, using last known good copy", err)
+	}
+	return nil, err
+}
+
+// Poll fetches the document every interval until ctx is done and calls
+// onChange whenever a new version is served or a request fails. An interval
+// that is not positive means DEFAULT_HTTP_POLL_INTERVAL. Use Builder.Poll to
+// have failures reported in Errs.
+func (s *HttpSource) Poll(ctx context.Context, interval time.Duration, onChange func(map[string]any, error)) {
+	if interval <= 0 {
+		interval = DEFAULT_HTTP_POLL_INTERVAL
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			data, changed, err := s.fetch(ctx)
+			if err != nil {
+				onChange(nil, err)
+			} else if changed {
+				onChange(data, nil)
+			}
+		}
+	}
+}
+
+func (s *HttpSource) fetch(ctx context.Context) (map[string]any, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.last == nil && s.cacheFile != "" {
+		s.readCache()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	for k, v := range s.headers {
+		req.Header[k] = v
+	}
+	if s.last != nil {
+		if s.etag != "" {
+			req.Header.Set("If-None-Match", s.etag)
+		}
+		if s.lastModified != "" {
+			req.Header.Set("If-Modified-Since", s.lastModified)
+		}
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && s.last != nil:
+		return deepCopyMap(s.last), false, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, false, fmt.Errorf("GET %s: %s", s.url, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, err
+	}
+	format := s.detectFormat(resp.Header.Get("Content-Type"))
+	data, err := decodeFormat(format, body, s.profile)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", s.url, err)
+	}
+
+	s.last = data
+	s.etag = resp.Header.Get("ETag")
+	s.lastModified = resp.Header.Get("Last-Modified")
+	if s.cacheFile != "" {
+		if err := s.writeCache(format, body); err != nil {
+			return deepCopyMap(data), true, fmt.Errorf("cannot write cache %s: %w", s.cacheFile, err)
+		}
+	}
+	return deepCopyMap(data), true, nil
+}
+
+func (s *HttpSource) detectFormat(contentType string) string {
+	if s.format != "" {
+		return s.format
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		switch {
+		case strings.HasSuffix(mediaType, "json"):
+			return JSON_SOURCE_NAME
+		case strings.HasSuffix(mediaType, "yaml"):
+			return YAML_SOURCE_NAME
+		case strings.HasSuffix(mediaType, "toml"):
+			return TOML_SOURCE_NAME
+		}
+	}
+	if u, err := url.Parse(s.url); err == nil {
+		if format := normalizeFormat(path.Ext(u.Path)); format != "" {
+			return format
+		}
+	}
+	return JSON_SOURCE_NAME
+}
+
+func (s *HttpSource) readCache() {
+	raw, err := os.ReadFile(s.cacheFile)
+	if err != nil {
+		return
+	}
+	var cache httpCache
+	if err := json.Unmarshal(raw, &cache); err != nil {
+		return
+	}
+	data, err := decodeFormat(cache.Format, []byte(cache.Body), s.profile)
+	if err != nil {
+		return
+	}
+	s.last = data
+	s.etag = cache.ETag
+	s.lastModified = cache.LastModified
+}
+
+func (s *HttpSource) writeCache(format string, body []byte) error {
+	raw, err := json.Marshal(httpCache{
+		ETag:         s.etag,
+		LastModified: s.lastModified,
+		Format:       format,
+		Body:         string(body),
+	})
+	if err != nil {
+		return err
+	}
+	return atomicfile.WriteFile(s.cacheFile, raw, 0o600)
+}
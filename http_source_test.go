@@ -0,0 +1,196 @@
+package ascanius
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type configServer struct {
+	mu          sync.Mutex
+	body        string
+	contentType string
+	etag        string
+	requests    int
+	notModified int
+	auth        string
+}
+
+func (c *configServer) set(body, etag string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.body, c.etag = body, etag
+}
+
+func (c *configServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests++
+	c.auth = r.Header.Get("Authorization")
+	if r.Header.Get("If-None-Match") == c.etag {
+		c.notModified++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", c.etag)
+	w.Header().Set("Content-Type", c.contentType)
+	w.Write([]byte(c.body))
+}
+
+func TestHttpSource(t *testing.T) {
+	cs := &configServer{contentType: "application/yaml; charset=utf-8"}
+	cs.set("mongo:\n  host: remote.mongo\n  port: 27017\n", `"v1"`)
+	server := httptest.NewServer(cs)
+
+	cache := filepath.Join(t.TempDir(), "config.cache")
+	src := NewHttpSource(server.URL+"/config", "remote", 10,
+		WithHttpHeader("Authorization", "Bearer abc"),
+		WithHttpCache(cache),
+		WithHttpTimeout(time.Second),
+	)
+
+	var cfg struct {
+		Mongo MongoConfig
+	}
+	builder := New().Source("./files/config.toml", 1).AddSource(src).Load(&cfg)
+	require.False(t, builder.HasErrs(), builder.Errs())
+	require.Equal(t, "remote.mongo", cfg.Mongo.Host)
+	require.Equal(t, uint16(27017), cfg.Mongo.Port)
+	require.Equal(t, "toml-db", cfg.Mongo.Database)
+	cs.mu.Lock()
+	require.Equal(t, "Bearer abc", cs.auth)
+	cs.mu.Unlock()
+
+	// unchanged documents are answered with 304
+	data, err := src.Load()
+	require.NoError(t, err)
+	require.Equal(t, "remote.mongo", data["mongo"].(map[string]any)["host"])
+	cs.mu.Lock()
+	require.Equal(t, 1, cs.notModified)
+	cs.mu.Unlock()
+
+	// a new source instance starts from the on-disk cache while the server is down
+	server.Close()
+	offline := NewHttpSource(server.URL+"/config", "remote", 10, WithHttpCache(cache))
+	var fallback struct {
+		Mongo MongoConfig
+	}
+	builder = New().AddSource(offline).Load(&fallback)
+	require.Len(t, builder.Errs(), 1)
+	require.Contains(t, builder.Errs()[0].Error(), "using last known good copy")
+	require.Equal(t, "remote.mongo", fallback.Mongo.Host)
+
+	// without cache nothing can be loaded
+	_, err = NewHttpSource(server.URL+"/config", "", 1).Load()
+	require.Error(t, err)
+}
+
+func TestHttpSourcePoll(t *testing.T) {
+	cs := &configServer{contentType: "application/json"}
+	cs.set(`{"port": 1}`, `"v1"`)
+	server := httptest.NewServer(cs)
+	defer server.Close()
+
+	src := NewHttpSource(server.URL, "", 1)
+	_, err := src.Load()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan map[string]any, 1)
+	go src.Poll(ctx, 10*time.Millisecond, func(data map[string]any, err error) {
+		if err == nil {
+			changes <- data
+		}
+	})
+
+	time.Sleep(50 * time.Millisecond)
+	cs.set(`{"port": 2}`, `"v2"`)
+
+	select {
+	case data := <-changes:
+		require.Equal(t, int64(2), data["port"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change detected")
+	}
+	cs.mu.Lock()
+	require.Greater(t, cs.notModified, 0)
+	cs.mu.Unlock()
+}
+
+func TestHttpSourceFormatDetection(t *testing.T) {
+	require.Equal(t, TOML_SOURCE_NAME, NewHttpSource("http://cfg/app.toml", "", 1).detectFormat("text/plain"))
+	require.Equal(t, YAML_SOURCE_NAME, NewHttpSource("http://cfg/app", "", 1).detectFormat("application/x-yaml"))
+	require.Equal(t, YAML_SOURCE_NAME, NewHttpSource("http://cfg/app.json", "", 1, WithHttpFormat("yml")).detectFormat("application/json"))
+	require.Equal(t, JSON_SOURCE_NAME, NewHttpSource("http://cfg/app", "", 1).detectFormat(""))
+}
+
+func TestHttpSourceYaml(t *testing.T) {
+	server := &configServer{contentType: "application/yaml"}
+	server.set(profilesYaml, `"v1"`)
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	data, err := NewHttpSource(ts.URL, "", 1, WithHttpProfile("prod")).Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"host": "mongo.internal", "port": 27017}, data["mongo"])
+
+	server.set("a: &a [1, 2]\nb: *a\n8080: http\n", `"v2"`)
+	data, err = NewHttpSource(ts.URL, "", 1).Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"a": []any{1, 2}, "b": []any{1, 2}, "8080": "http"}, data)
+
+	server.set("a: &a\n  b: *a\n", `"v3"`)
+	_, err = NewHttpSource(ts.URL, "", 1).Load()
+	require.ErrorContains(t, err, "contains itself")
+}
+
+func TestBuilderPoll(t *testing.T) {
+	cs := &configServer{contentType: "application/json"}
+	cs.set(`{"port": 1}`, `"v1"`)
+	server := httptest.NewServer(cs)
+
+	src := NewHttpSource(server.URL, "remote", 1)
+	require.NotNil(t, src.client.Transport.(*http.Transport).Proxy)
+
+	var cfg struct {
+		Port int
+	}
+	builder := New().AddSource(src).Load(&cfg)
+	require.False(t, builder.HasErrs(), builder.Errs())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	failures := make(chan error, 1)
+	go builder.Poll(ctx, src, 10*time.Millisecond, func(_ map[string]any, err error) {
+		if err != nil {
+			select {
+			case failures <- err:
+			default:
+			}
+		}
+	})
+
+	server.Close()
+	select {
+	case <-failures:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no failure reported")
+	}
+	require.True(t, builder.HasErrs())
+	var serr *SourceError
+	require.ErrorAs(t, builder.Errs()[0], &serr)
+	require.Equal(t, "remote", serr.Source)
+
+	// a zero interval falls back to the default instead of panicking
+	stopped, stop := context.WithCancel(context.Background())
+	stop()
+	src.Poll(stopped, 0, nil)
+}
@@ -0,0 +1,217 @@
+package ascanius
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const INI_SOURCE_NAME = "ini"
+
+// IniSource reads INI files. Sections, including dotted ones such as
+// [db.pool], become nested maps, and dotted keys are expanded the same way.
+// Values get the scalar inference of .env files unless they are quoted. A
+// line ending with a backslash continues on the next one. The source is
+// read-only.
+type IniSource struct {
+	name     string
+	path     string
+	priority int
+}
+
+func NewIniSource(path string, name string, priority int) *IniSource {
+	if name == "" {
+		name = path
+	}
+	return &IniSource{
+		name:     name,
+		path:     path,
+		priority: priority,
+	}
+}
+
+func (s *IniSource) Load() (map[string]any, error) {
+	bytes, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := decodeIni(bytes)
+	if err != nil {
+		return nil, newParseError(s.path, bytes, err)
+	}
+	return result, nil
+}
+
+func (s *IniSource) Name() string {
+	return s.name
+}
+
+func (s *IniSource) SetName(name string) {
+	s.name = name
+}
+
+func (s *IniSource) Priority() int {
+	return s.priority
+}
+
+func (s *IniSource) SetPriority(p int) {
+	s.priority = p
+}
+
+func (s *IniSource) Type() string {
+	return INI_SOURCE_NAME
+}
+
+// lineError is a syntax error on a 1-based line of a line-oriented format.
+type lineError struct {
+	line int
+	msg  string
+}
+
+func (e *lineError) Error() string {
+	return e.msg
+}
+
+func decodeIni(content []byte) (map[string]any, error) {
+	root := make(map[string]any)
+	var section []string
+
+	lines := strings.Split(strings.TrimPrefix(string(content), "\uFEFF"), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimSpace(strings.TrimSuffix(lines[i], "\r"))
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+		for strings.HasSuffix(line, `\`) && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimSpace(strings.TrimSuffix(lines[i], "\r"))
+		}
+
+		if line[0] == '[' {
+			end := strings.IndexByte(line, ']')
+			if end < 0 {
+				return nil, &lineError{line: lineNo, msg: "unterminated section header"}
+			}
+			if rest := strings.TrimSpace(line[end+1:]); rest != "" && rest[0] != ';' && rest[0] != '#' {
+				return nil, &lineError{line: lineNo, msg: fmt.Sprintf("unexpected %q after section header", rest)}
+			}
+			parts, ok := splitDottedKey(strings.TrimSpace(line[1:end]))
+			if !ok {
+				return nil, &lineError{line: lineNo, msg: fmt.Sprintf("invalid section name %q", line[1:end])}
+			}
+			if _, err := dottedSection(root, parts); err != nil {
+				return nil, &lineError{line: lineNo, msg: err.Error()}
+			}
+			section = parts
+			continue
+		}
+
+		sep := strings.IndexAny(line, "=:")
+		if sep < 0 {
+			return nil, &lineError{line: lineNo, msg: fmt.Sprintf("expected key = value, found %q", line)}
+		}
+		key := strings.TrimSpace(line[:sep])
+		parts, ok := splitDottedKey(key)
+		if !ok {
+			return nil, &lineError{line: lineNo, msg: fmt.Sprintf("invalid key %q", key)}
+		}
+		value, err := iniValue(strings.TrimSpace(line[sep+1:]))
+		if err != nil {
+			return nil, &lineError{line: lineNo, msg: fmt.Sprintf("%s: %v", key, err)}
+		}
+		path := append(append([]string{}, section...), parts...)
+		if err := setDotted(root, path, value); err != nil {
+			return nil, &lineError{line: lineNo, msg: err.Error()}
+		}
+	}
+	return root, nil
+}
+
+// iniValue returns the value of a key. Text in double quotes may contain
+// escapes such as \n and \u00e9, text in single quotes is taken as it is,
+// and unquoted values, without their trailing ; or # comment, are inferred
+// like .env values.
+func iniValue(raw string) (any, error) {
+	if raw == "" {
+		return "", nil
+	}
+
+	var value string
+	switch quote := raw[0]; quote {
+	case '"', '\'':
+		end := 1
+		for ; end < len(raw) && raw[end] != quote; end++ {
+			if quote == '"' && raw[end] == '\\' {
+				end++
+			}
+		}
+		if end >= len(raw) {
+			return nil, fmt.Errorf("unterminated string %s", raw)
+		}
+		if rest := strings.TrimSpace(raw[end+1:]); rest != "" && rest[0] != ';' && rest[0] != '#' {
+			return nil, fmt.Errorf("unexpected %q after quoted value", rest)
+		}
+		value = raw[1:end]
+		if quote == '\'' {
+			return value, nil
+		}
+		return unescapeProperties(value)
+	}
+
+	value = raw
+	for i := 1; i < len(value); i++ {
+		if (value[i] == ';' || value[i] == '#') && (value[i-1] == ' ' || value[i-1] == '\t') {
+			value = strings.TrimSpace(value[:i])
+			break
+		}
+	}
+	return inferValue(value), nil
+}
+
+// splitDottedKey splits a dotted key path, reporting false when a part is
+// empty.
+func splitDottedKey(key string) ([]string, bool) {
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		if parts[i] = strings.TrimSpace(part); parts[i] == "" {
+			return nil, false
+		}
+	}
+	return parts, true
+}
+
+// setDotted sets the value at path in root, creating the maps on the way.
+// Unlike expandEnv, it fails when a key would hold both a value and nested
+// keys.
+func setDotted(root map[string]any, path []string, value any) error {
+	parent, err := dottedSection(root, path[:len(path)-1])
+	if err != nil {
+		return err
+	}
+	last := path[len(path)-1]
+	if _, isMap := parent[last].(map[string]any); isMap {
+		return fmt.Errorf("%s is both a section and a value", strings.Join(path, "."))
+	}
+	parent[last] = value
+	return nil
+}
+
+// dottedSection returns the map at path in root, creating it if needed.
+func dottedSection(root map[string]any, path []string) (map[string]any, error) {
+	current := root
+	for i, part := range path {
+		existing, exists := current[part]
+		next, isMap := existing.(map[string]any)
+		switch {
+		case !exists:
+			next = make(map[string]any)
+			current[part] = next
+		case !isMap:
+			return nil, fmt.Errorf("%s is both a section and a value", strings.Join(path[:i+1], "."))
+		}
+		current = next
+	}
+	return current, nil
+}
@@ -0,0 +1,82 @@
+package ascanius
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIniSource(t *testing.T) {
+	path := writeTemp(t, "legacy.ini", `; global keys
+name = api
+debug = true
+
+[mongo]
+host = localhost   ; inline comment
+port: 27017
+replica.set = rs0
+hosts = ["a", "b"]
+
+[mongo.tls]
+enabled = yes
+ca = "C:\\certs\\ca.pem"
+motd = "caf\u00e9\n"
+raw = 'not # a comment'
+flags = one, \
+        two
+`)
+	data, err := NewIniSource(path, "", 1).Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"name":  "api",
+		"debug": true,
+		"mongo": map[string]any{
+			"host":    "localhost",
+			"port":    int64(27017),
+			"replica": map[string]any{"set": "rs0"},
+			"hosts":   []any{"a", "b"},
+			"tls": map[string]any{
+				"enabled": "yes",
+				"ca":      `C:\certs\ca.pem`,
+				"motd":    "café\n",
+				"raw":     "not # a comment",
+				"flags":   "one, two",
+			},
+		},
+	}, data)
+
+	var cfg struct {
+		Mongo struct {
+			Host string
+			Port int
+		}
+	}
+	require.NoError(t, New().Source(path, 1).Load(&cfg).Err())
+	assert.Equal(t, "localhost", cfg.Mongo.Host)
+	assert.Equal(t, 27017, cfg.Mongo.Port)
+}
+
+func TestIniErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		line    int
+		msg     string
+	}{
+		{"missing separator", "[a]\nhost\n", 2, "expected key = value"},
+		{"unterminated section", "[a\n", 1, "unterminated section header"},
+		{"empty section", "[]\n", 1, "invalid section name"},
+		{"value and section", "db = 1\n[db]\nhost = x\n", 2, "db is both a section and a value"},
+		{"unterminated string", "a = \"x\n", 1, "unterminated string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewIniSource(writeTemp(t, "config.ini", tt.content), "", 1).Load()
+			var perr *ParseError
+			require.ErrorAs(t, err, &perr)
+			assert.Equal(t, tt.line, perr.Line)
+			assert.Contains(t, perr.Err.Error(), tt.msg)
+		})
+	}
+}
@@ -0,0 +1,514 @@
+package ascanius
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const JSON5_SOURCE_NAME = "json5"
+
+// Json5Source reads JSONC and JSON5 files: JSON with comments, trailing
+// commas, unquoted keys, single-quoted strings, hex numbers, Infinity and
+// NaN. Like JsonSource, integers are decoded as int64 and other numbers as
+// float64. The source is read-only, since writing it back would drop the
+// comments.
+type Json5Source struct {
+	name     string
+	path     string
+	priority int
+}
+
+func NewJson5Source(path string, name string, priority int) *Json5Source {
+	if name == "" {
+		name = path
+	}
+	return &Json5Source{
+		name:     name,
+		path:     path,
+		priority: priority,
+	}
+}
+
+func (j *Json5Source) Load() (map[string]any, error) {
+	bytes, err := os.ReadFile(j.path)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := decodeJson5(bytes)
+	if err != nil {
+		return nil, newParseError(j.path, bytes, err)
+	}
+	return result, nil
+}
+
+func (j *Json5Source) Name() string {
+	return j.name
+}
+
+func (j *Json5Source) SetName(name string) {
+	j.name = name
+}
+
+func (j *Json5Source) Priority() int {
+	return j.priority
+}
+
+func (j *Json5Source) SetPriority(p int) {
+	j.priority = p
+}
+
+func (j *Json5Source) Type() string {
+	return JSON5_SOURCE_NAME
+}
+
+// syntaxError is a syntax error at a byte offset. Like json.SyntaxError,
+// offset is just past the offending byte.
+type syntaxError struct {
+	msg    string
+	offset int64
+}
+
+func (e *syntaxError) Error() string {
+	return e.msg
+}
+
+type json5Parser struct {
+	data []byte
+	pos  int
+}
+
+// decodeJson5 parses a JSON5 document whose top-level value is an object.
+func decodeJson5(data []byte) (map[string]any, error) {
+	p := &json5Parser{data: data}
+	if err := p.skipSpace(); err != nil {
+		return nil, err
+	}
+	start := p.pos
+	value, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.skipSpace(); err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.data) {
+		return nil, p.errorf("unexpected %q after the top-level value", p.peekRune())
+	}
+
+	m, ok := value.(map[string]any)
+	if !ok {
+		p.pos = start
+		return nil, p.errorf("the top-level value must be an object")
+	}
+	return m, nil
+}
+
+func (p *json5Parser) errorf(format string, args ...any) error {
+	return &syntaxError{msg: fmt.Sprintf(format, args...), offset: int64(p.pos) + 1}
+}
+
+func (p *json5Parser) eof() bool {
+	return p.pos >= len(p.data)
+}
+
+func (p *json5Parser) peekRune() rune {
+	r, _ := utf8.DecodeRune(p.data[p.pos:])
+	return r
+}
+
+// skipSpace skips white space and comments.
+func (p *json5Parser) skipSpace() error {
+	for !p.eof() {
+		r, size := utf8.DecodeRune(p.data[p.pos:])
+		switch {
+		case unicode.IsSpace(r) || r == '\uFEFF':
+			p.pos += size
+
+		case bytes.HasPrefix(p.data[p.pos:], []byte("//")):
+			end := bytes.IndexByte(p.data[p.pos:], '\n')
+			if end < 0 {
+				p.pos = len(p.data)
+			} else {
+				p.pos += end + 1
+			}
+
+		case bytes.HasPrefix(p.data[p.pos:], []byte("/*")):
+			end := bytes.Index(p.data[p.pos+2:], []byte("*/"))
+			if end < 0 {
+				return p.errorf("unterminated comment")
+			}
+			p.pos += end + 4
+
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (p *json5Parser) value() (any, error) {
+	if p.eof() {
+		return nil, p.errorf("unexpected end of input")
+	}
+	switch c := p.data[p.pos]; {
+	case c == '{':
+		return p.object()
+	case c == '[':
+		return p.array()
+	case c == '"' || c == '\'':
+		return p.string()
+	case c == '-' || c == '+' || c == '.' || isDigit(c):
+		return p.number()
+	}
+
+	start := p.pos
+	switch ident := p.identifier(); ident {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	case "Infinity", "NaN":
+		p.pos = start
+		return p.number()
+	default:
+		p.pos = start
+		return nil, p.errorf("invalid character %q looking for a value", p.peekRune())
+	}
+}
+
+func (p *json5Parser) object() (any, error) {
+	p.pos++
+	m := make(map[string]any)
+	for {
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if p.eof() {
+			return nil, p.errorf("unterminated object")
+		}
+		if p.data[p.pos] == '}' {
+			p.pos++
+			return m, nil
+		}
+
+		var key string
+		switch c := p.data[p.pos]; {
+		case c == '"' || c == '\'':
+			s, err := p.string()
+			if err != nil {
+				return nil, err
+			}
+			key = s
+		default:
+			if key = p.identifier(); key == "" {
+				return nil, p.errorf("invalid character %q looking for a key", p.peekRune())
+			}
+		}
+
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if p.eof() || p.data[p.pos] != ':' {
+			return nil, p.errorf("expected ':' after key %q", key)
+		}
+		p.pos++
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		m[key] = value
+
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		switch {
+		case p.eof():
+			return nil, p.errorf("unterminated object")
+		case p.data[p.pos] == ',':
+			p.pos++
+		case p.data[p.pos] != '}':
+			return nil, p.errorf("invalid character %q after object value", p.peekRune())
+		}
+	}
+}
+
+func (p *json5Parser) array() (any, error) {
+	p.pos++
+	out := []any{}
+	for {
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if p.eof() {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.data[p.pos] == ']' {
+			p.pos++
+			return out, nil
+		}
+
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, value)
+
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		switch {
+		case p.eof():
+			return nil, p.errorf("unterminated array")
+		case p.data[p.pos] == ',':
+			p.pos++
+		case p.data[p.pos] != ']':
+			return nil, p.errorf("invalid character %q after array element", p.peekRune())
+		}
+	}
+}
+
+// identifier reads an unquoted key or keyword, returning "" when there is
+// none at the current position.
+func (p *json5Parser) identifier() string {
+	start := p.pos
+	for !p.eof() {
+		r, size := utf8.DecodeRune(p.data[p.pos:])
+		if !isIdentRune(r, p.pos == start) {
+			break
+		}
+		p.pos += size
+	}
+	return string(p.data[start:p.pos])
+}
+
+func isIdentRune(r rune, first bool) bool {
+	switch {
+	case r == '_' || r == '$' || unicode.IsLetter(r):
+		return true
+	case first:
+		return false
+	default:
+		return unicode.IsDigit(r) || unicode.In(r, unicode.Mn, unicode.Mc, unicode.Pc) || r == '\u200C' || r == '\u200D'
+	}
+}
+
+func (p *json5Parser) string() (string, error) {
+	quote := rune(p.data[p.pos])
+	p.pos++
+	var sb strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated string")
+		}
+		r, size := utf8.DecodeRune(p.data[p.pos:])
+		switch r {
+		case quote:
+			p.pos += size
+			return sb.String(), nil
+		case '\n', '\r':
+			// point at the end of the line rather than the start of the next
+			p.pos--
+			return "", p.errorf("unterminated string")
+		case '\\':
+			p.pos++
+			if err := p.escape(&sb); err != nil {
+				return "", err
+			}
+		default:
+			sb.WriteRune(r)
+			p.pos += size
+		}
+	}
+}
+
+var json5Escapes = map[rune]string{
+	'b': "\b", 'f': "\f", 'n': "\n", 'r': "\r", 't': "\t", 'v': "\v", '0': "\x00",
+}
+
+// escape decodes the escape sequence after a backslash.
+func (p *json5Parser) escape(sb *strings.Builder) error {
+	if p.eof() {
+		return p.errorf("unterminated string")
+	}
+	r, size := utf8.DecodeRune(p.data[p.pos:])
+	switch {
+	case json5Escapes[r] != "":
+		if r == '0' && p.pos+1 < len(p.data) && isDigit(p.data[p.pos+1]) {
+			return p.errorf("invalid escape sequence \\0%c", p.data[p.pos+1])
+		}
+		sb.WriteString(json5Escapes[r])
+		p.pos++
+
+	case r == 'x':
+		p.pos++
+		code, err := p.hex(2)
+		if err != nil {
+			return err
+		}
+		sb.WriteRune(rune(code))
+
+	case r == 'u':
+		p.pos++
+		code, err := p.hex(4)
+		if err != nil {
+			return err
+		}
+		decoded := rune(code)
+		if utf16.IsSurrogate(decoded) && bytes.HasPrefix(p.data[p.pos:], []byte(`\u`)) {
+			start := p.pos
+			p.pos += 2
+			low, err := p.hex(4)
+			if err != nil {
+				return err
+			}
+			if pair := utf16.DecodeRune(decoded, rune(low)); pair != utf8.RuneError {
+				decoded = pair
+			} else {
+				p.pos = start
+			}
+		}
+		sb.WriteRune(decoded)
+
+	case r == '\r':
+		// a line continuation
+		p.pos++
+		if !p.eof() && p.data[p.pos] == '\n' {
+			p.pos++
+		}
+
+	case r == '\n' || r == '\u2028' || r == '\u2029':
+		p.pos += size
+
+	case r >= '1' && r <= '9':
+		return p.errorf("invalid escape sequence \\%c", r)
+
+	default:
+		sb.WriteRune(r)
+		p.pos += size
+	}
+	return nil
+}
+
+func (p *json5Parser) hex(digits int) (uint64, error) {
+	if p.pos+digits > len(p.data) {
+		return 0, p.errorf("invalid escape sequence")
+	}
+	code, err := strconv.ParseUint(string(p.data[p.pos:p.pos+digits]), 16, 32)
+	if err != nil {
+		return 0, p.errorf("invalid escape sequence")
+	}
+	p.pos += digits
+	return code, nil
+}
+
+// number reads a number, as an int64 when it is an integer in range and as a
+// float64 otherwise.
+func (p *json5Parser) number() (any, error) {
+	start := p.pos
+	negative := false
+	if c := p.data[p.pos]; c == '+' || c == '-' {
+		negative = c == '-'
+		p.pos++
+	}
+
+	rest := p.data[p.pos:]
+	var value any
+	switch {
+	case bytes.HasPrefix(rest, []byte("Infinity")):
+		p.pos += len("Infinity")
+		value = math.Inf(1)
+		if negative {
+			value = math.Inf(-1)
+		}
+
+	case bytes.HasPrefix(rest, []byte("NaN")):
+		p.pos += len("NaN")
+		value = math.NaN()
+
+	case bytes.HasPrefix(rest, []byte("0x")) || bytes.HasPrefix(rest, []byte("0X")):
+		p.pos += 2
+		digits := p.pos
+		for !p.eof() && isHexDigit(p.data[p.pos]) {
+			p.pos++
+		}
+		hex := string(p.data[digits:p.pos])
+		if negative {
+			hex = "-" + hex
+		}
+		if n, err := strconv.ParseInt(hex, 16, 64); err == nil {
+			value = n
+		} else if n, err := strconv.ParseUint(strings.TrimPrefix(hex, "-"), 16, 64); err == nil {
+			value = float64(n)
+			if negative {
+				value = -float64(n)
+			}
+		} else {
+			p.pos = start
+			return nil, p.errorf("invalid number %s", p.numberText())
+		}
+
+	default:
+		p.skipDigits()
+		integer := true
+		if !p.eof() && p.data[p.pos] == '.' {
+			integer = false
+			p.pos++
+			p.skipDigits()
+		}
+		if !p.eof() && (p.data[p.pos] == 'e' || p.data[p.pos] == 'E') {
+			integer = false
+			p.pos++
+			if !p.eof() && (p.data[p.pos] == '+' || p.data[p.pos] == '-') {
+				p.pos++
+			}
+			p.skipDigits()
+		}
+		text := string(p.data[start:p.pos])
+		if n, err := strconv.ParseInt(text, 10, 64); integer && err == nil {
+			value = n
+		} else if f, err := strconv.ParseFloat(text, 64); err == nil {
+			value = f
+		} else {
+			p.pos = start
+			return nil, p.errorf("invalid number %s", p.numberText())
+		}
+	}
+
+	if !p.eof() && isIdentRune(p.peekRune(), false) {
+		p.pos = start
+		return nil, p.errorf("invalid number %s", p.numberText())
+	}
+	return value, nil
+}
+
+func (p *json5Parser) skipDigits() {
+	for !p.eof() && isDigit(p.data[p.pos]) {
+		p.pos++
+	}
+}
+
+// numberText returns the token at the current position, for errors.
+func (p *json5Parser) numberText() string {
+	end := p.pos
+	for end < len(p.data) && !unicode.IsSpace(rune(p.data[end])) && strings.IndexByte(",]}/", p.data[end]) < 0 {
+		end++
+	}
+	return string(p.data[p.pos:end])
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
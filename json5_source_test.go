@@ -0,0 +1,126 @@
+package ascanius
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJson5Source(t *testing.T) {
+	path := writeTemp(t, "config.json5", `// settings
+{
+  name: 'api',         // unquoted key, single quotes
+  "log-level": "debug",
+  $port: 0x1F90,
+  ratio: .5,
+  limit: +1e3,
+  offset: -0x10,
+  id: 9007199254740993,
+  /* block
+     comment */
+  hosts: [
+    "a",
+    'b\'s',
+  ],
+  text: "tab\there é 😀 \x41 line \
+continued",
+  mongo: {tls: true, auth: null, max: Infinity,},
+}
+`)
+	data, err := NewJson5Source(path, "", 1).Load()
+	require.NoError(t, err)
+	assert.Equal(t, "api", data["name"])
+	assert.Equal(t, "debug", data["log-level"])
+	assert.Equal(t, int64(8080), data["$port"])
+	assert.Equal(t, 0.5, data["ratio"])
+	assert.Equal(t, float64(1000), data["limit"])
+	assert.Equal(t, int64(-16), data["offset"])
+	assert.Equal(t, int64(9007199254740993), data["id"])
+	assert.Equal(t, []any{"a", "b's"}, data["hosts"])
+	assert.Equal(t, "tab\there é 😀 A line continued", data["text"])
+	mongo := data["mongo"].(map[string]any)
+	assert.Equal(t, true, mongo["tls"])
+	assert.Nil(t, mongo["auth"])
+	assert.True(t, math.IsInf(mongo["max"].(float64), 1))
+}
+
+func TestJson5NonFiniteNumbers(t *testing.T) {
+	path := writeTemp(t, "limits.json5", `{max: Infinity, min: -Infinity, ratio: NaN}`)
+	type limits struct {
+		Max   float64
+		Min   float64
+		Ratio float64
+	}
+	var cfg limits
+	builder := New().Source(path, 1).Load(&cfg)
+	require.False(t, builder.HasErrs(), builder.Errs())
+
+	want := `{"max":"Infinity","min":"-Infinity","ratio":"NaN"}`
+	assert.Equal(t, want, builder.String())
+	assert.Equal(t, want, builder.Redacted(nil).String())
+	assert.Equal(t, want, builder.Redacted(&cfg).String())
+
+	for format, name := range map[string]string{"json": "limits.json", "env": ".env"} {
+		var buf bytes.Buffer
+		require.NoError(t, builder.Export(&buf, format), format)
+		exported := writeTemp(t, name, buf.String())
+
+		var reloaded limits
+		require.False(t, New().Source(exported, 1).Load(&reloaded).HasErrs(), format)
+		assert.True(t, math.IsInf(reloaded.Max, 1), format)
+		assert.True(t, math.IsInf(reloaded.Min, -1), format)
+		assert.True(t, math.IsNaN(reloaded.Ratio), format)
+	}
+}
+
+func TestJsoncBuilder(t *testing.T) {
+	path := writeTemp(t, "settings.jsonc", `{
+  // editor settings
+  "mongo": {
+    "host": "localhost",
+    "port": 27017,
+  },
+}
+`)
+	var cfg struct {
+		Mongo struct {
+			Host string
+			Port int
+		}
+	}
+	require.NoError(t, New().Source(path, 1).Load(&cfg).Err())
+	assert.Equal(t, "localhost", cfg.Mongo.Host)
+	assert.Equal(t, 27017, cfg.Mongo.Port)
+}
+
+func TestJson5Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		line    int
+		column  int
+		msg     string
+	}{
+		{"missing comma", "{\n  a: 1\n  b: 2\n}", 3, 3, `invalid character 'b' after object value`},
+		{"bad key", "{\n  -a: 1\n}", 2, 3, `looking for a key`},
+		{"bad number", "{a: 12abc}", 1, 5, "invalid number 12abc"},
+		{"unterminated string", "{a: 'x\n}", 1, 6, "unterminated string"},
+		{"unterminated comment", "{a: 1 /* x", 1, 7, "unterminated comment"},
+		{"not an object", "[1, 2]", 1, 1, "must be an object"},
+		{"trailing value", "{} {}", 1, 4, "after the top-level value"},
+		{"bad escape", `{a: "\1"}`, 1, 7, "invalid escape sequence"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewJson5Source(writeTemp(t, "config.jsonc", tt.content), "", 1).Load()
+			var perr *ParseError
+			require.ErrorAs(t, err, &perr)
+			assert.Equal(t, tt.line, perr.Line)
+			assert.Equal(t, tt.column, perr.Column)
+			assert.Contains(t, perr.Err.Error(), tt.msg)
+		})
+	}
+}
@@ -1,8 +1,14 @@
 package ascanius
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"slices"
+	"strings"
 )
 
 const JSON_SOURCE_NAME = "json"
@@ -11,6 +17,7 @@ type JsonSource struct {
 	name     string
 	path     string
 	priority int
+	state    *fileState
 }
 
 func NewJsonSource(path string, name string, priority int) *JsonSource {
@@ -21,6 +28,7 @@ func NewJsonSource(path string, name string, priority int) *JsonSource {
 		name:     name,
 		path:     path,
 		priority: priority,
+		state:    &fileState{},
 	}
 }
 
@@ -32,13 +40,40 @@ func (j *JsonSource) Load() (map[string]any, error) {
 		return nil, err
 	}
 
-	if err := json.Unmarshal(bytes, &result); err != nil {
-		return nil, err
+	if err := unmarshalJson(bytes, &result); err != nil {
+		return nil, newParseError(j.path, bytes, err)
 	}
+	j.state.loaded(bytes)
 
 	return result, nil
 }
 
+// unmarshalJson decodes data like json.Unmarshal, except that numbers are
+// decoded as int64 when they are integers, so that they keep their
+// precision, and as float64 otherwise.
+func unmarshalJson(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	start := dec.InputOffset()
+	if _, err := dec.Token(); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	} else if err == nil {
+		offset := start + int64(len(data[start:])-len(bytes.TrimLeft(data[start:], " \t\r\n")))
+		return &syntaxError{msg: "invalid character after top-level value", offset: offset + 1}
+	}
+
+	switch val := v.(type) {
+	case *map[string]any:
+		numbersToValues(*val)
+	case *any:
+		*val = numbersToValues(*val)
+	}
+	return nil
+}
+
 func (j *JsonSource) Name() string {
 	return j.name
 }
@@ -58,3 +93,235 @@ func (j *JsonSource) SetPriority(p int) {
 func (j *JsonSource) Type() string {
 	return JSON_SOURCE_NAME
 }
+
+// Set records a new value for key, written by Save.
+func (j *JsonSource) Set(key string, value any) error {
+	return j.state.set(j.path, key, value)
+}
+
+// Save writes the values recorded by Set, keeping the order of existing
+// keys. The file is re-indented.
+func (j *JsonSource) Save() error {
+	return j.state.save(j.path, editJson)
+}
+
+func (j *JsonSource) hasPending() bool {
+	return j.state.hasPending()
+}
+
+// jsonObject is a decoded JSON object that remembers the order of its keys.
+type jsonObject struct {
+	keys   []string
+	values map[string]any
+}
+
+func (o *jsonObject) set(key string, value any) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+func (o *jsonObject) remove(key string) {
+	delete(o.values, key)
+	o.keys = slices.DeleteFunc(o.keys, func(k string) bool { return k == key })
+}
+
+// editJson sets or removes e in a JSON document. Keys are matched after
+// normalization and new keys are appended to their object.
+func editJson(content []byte, e fileEdit) ([]byte, error) {
+	root := &jsonObject{values: make(map[string]any)}
+	if len(bytes.TrimSpace(content)) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(content))
+		dec.UseNumber()
+		doc, err := decodeOrderedJson(dec)
+		if err != nil {
+			return nil, err
+		}
+		obj, ok := doc.(*jsonObject)
+		if !ok {
+			return nil, errors.New("the document is not a JSON object")
+		}
+		root = obj
+	}
+
+	parts := strings.Split(e.key, ".")
+	if e.remove {
+		if !removeJson(root, parts) {
+			return content, nil
+		}
+	} else {
+		setJson(root, parts, e.value)
+	}
+
+	var buf bytes.Buffer
+	if err := encodeOrderedJson(&buf, root, detectIndent(content, "  "), ""); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+func setJson(root *jsonObject, parts []string, value any) {
+	obj := root
+	for _, part := range parts[:len(parts)-1] {
+		key := matchKey(obj.keys, part)
+		next, ok := obj.values[key].(*jsonObject)
+		if !ok {
+			next = &jsonObject{values: make(map[string]any)}
+			obj.set(key, next)
+		}
+		obj = next
+	}
+	last := parts[len(parts)-1]
+	obj.set(matchKey(obj.keys, last), value)
+}
+
+// removeJson removes the key at parts and the objects it leaves empty,
+// reporting whether the key was found.
+func removeJson(obj *jsonObject, parts []string) bool {
+	key := matchKey(obj.keys, parts[0])
+	value, ok := obj.values[key]
+	if !ok {
+		return false
+	}
+	if len(parts) > 1 {
+		child, isObj := value.(*jsonObject)
+		if !isObj || !removeJson(child, parts[1:]) {
+			return false
+		}
+		if len(child.keys) > 0 {
+			return true
+		}
+	}
+	obj.remove(key)
+	return true
+}
+
+// matchKey returns the key in keys that normalizes to key, or key itself.
+func matchKey(keys []string, key string) string {
+	for _, k := range keys {
+		if toSnakeCase(k) == key {
+			return k
+		}
+	}
+	return key
+}
+
+func decodeOrderedJson(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		obj := &jsonObject{values: make(map[string]any)}
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrderedJson(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj.set(keyTok.(string), value)
+		}
+		_, err := dec.Token()
+		return obj, err
+	case json.Delim('['):
+		items := []any{}
+		for dec.More() {
+			item, err := decodeOrderedJson(dec)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		_, err := dec.Token()
+		return items, err
+	default:
+		return tok, nil
+	}
+}
+
+func encodeOrderedJson(w io.Writer, v any, indent, prefix string) error {
+	inner := prefix + indent
+	switch val := v.(type) {
+	case *jsonObject:
+		if len(val.keys) == 0 {
+			_, err := io.WriteString(w, "{}")
+			return err
+		}
+		io.WriteString(w, "{\n")
+		for i, k := range val.keys {
+			key, err := marshalJsonValue(k)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "%s%s: ", inner, key)
+			if err := encodeOrderedJson(w, val.values[k], indent, inner); err != nil {
+				return err
+			}
+			if i < len(val.keys)-1 {
+				io.WriteString(w, ",")
+			}
+			io.WriteString(w, "\n")
+		}
+		_, err := io.WriteString(w, prefix+"}")
+		return err
+
+	case []any:
+		if len(val) == 0 {
+			_, err := io.WriteString(w, "[]")
+			return err
+		}
+		io.WriteString(w, "[\n")
+		for i, item := range val {
+			io.WriteString(w, inner)
+			if err := encodeOrderedJson(w, item, indent, inner); err != nil {
+				return err
+			}
+			if i < len(val)-1 {
+				io.WriteString(w, ",")
+			}
+			io.WriteString(w, "\n")
+		}
+		_, err := io.WriteString(w, prefix+"]")
+		return err
+
+	case map[string]any:
+		// values given to Set
+		raw, err := json.MarshalIndent(val, prefix, indent)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(raw)
+		return err
+
+	default:
+		raw, err := marshalJsonValue(val)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(raw)
+		return err
+	}
+}
+
+func marshalJsonValue(v any) ([]byte, error) {
+	raw, err := marshalJson(v)
+	return bytes.TrimSuffix(raw, []byte("\n")), err
+}
+
+// detectIndent returns the indentation of the first indented line of
+// content, or def.
+func detectIndent(content []byte, def string) string {
+	for _, line := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" && len(trimmed) < len(line) {
+			return line[:len(line)-len(trimmed)]
+		}
+	}
+	return def
+}
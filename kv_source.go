@@ -0,0 +1,223 @@
+package ascanius
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const KV_SOURCE_NAME = "kv"
+
+// KVPair is a single entry of a hierarchical key/value store.
+type KVPair struct {
+	Key         string
+	Value       []byte
+	ModifyIndex uint64
+}
+
+// KVStore is the subset of a Consul-style key/value store used by KVSource.
+type KVStore interface {
+	// List returns every pair whose key starts with prefix.
+	List(ctx context.Context, prefix string) ([]KVPair, error)
+
+	// Watch blocks until the pairs under prefix change past index, or until
+	// the store's wait time elapses, and returns them with the new index.
+	Watch(ctx context.Context, prefix string, index uint64) ([]KVPair, uint64, error)
+}
+
+// KVSource loads every key below a prefix, e.g. "services/api/", and maps the
+// remaining path segments to nested maps: "services/api/mongo/host" becomes
+// {"mongo": {"host": ...}}. Leaf values are parsed like environment values.
+type KVSource struct {
+	name     string
+	prefix   string
+	priority int
+	store    KVStore
+}
+
+func NewKVSource(store KVStore, prefix string, name string, priority int) *KVSource {
+	if name == "" {
+		name = KV_SOURCE_NAME + ":" + prefix
+	}
+	return &KVSource{
+		name:     name,
+		prefix:   prefix,
+		priority: priority,
+		store:    store,
+	}
+}
+
+func (s *KVSource) Name() string        { return s.name }
+func (s *KVSource) SetName(name string) { s.name = name }
+func (s *KVSource) Priority() int       { return s.priority }
+func (s *KVSource) SetPriority(p int)   { s.priority = p }
+func (s *KVSource) Type() string        { return KV_SOURCE_NAME }
+
+func (s *KVSource) Load() (map[string]any, error) {
+	return s.LoadContext(context.Background())
+}
+
+func (s *KVSource) LoadContext(ctx context.Context) (map[string]any, error) {
+	pairs, err := s.store.List(ctx, s.prefix)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s.name, err)
+	}
+	return expandKV(pairs, s.prefix), nil
+}
+
+// Watch blocks until ctx is done and calls onChange with the new data every
+// time the store reports a new index for the prefix, or with the error when
+// a watch fails.
+func (s *KVSource) Watch(ctx context.Context, onChange func(map[string]any, error)) {
+	var index uint64
+	for {
+		pairs, next, err := s.store.Watch(ctx, s.prefix, index)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			onChange(nil, fmt.Errorf("%s: %w", s.name, err))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		if index != 0 && next != index {
+			onChange(expandKV(pairs, s.prefix), nil)
+		}
+		index = next
+	}
+}
+
+// expandKV nests pairs by their path below prefix. A key that is both a value
+// and a folder keeps its value under PROPERTIES_VALUE_KEY, like
+// PropertiesSource.
+func expandKV(pairs []KVPair, prefix string) map[string]any {
+	root := map[string]any{}
+
+	for _, pair := range pairs {
+		key := strings.Trim(strings.TrimPrefix(pair.Key, prefix), "/")
+		// folder entries carry no value
+		if key == "" || strings.HasSuffix(pair.Key, "/") {
+			continue
+		}
+		setProperty(root, strings.Split(key, "/"), inferValue(string(pair.Value)))
+	}
+
+	return root
+}
+
+// MemoryKVStore is an in-memory KVStore, meant as a test double.
+type MemoryKVStore struct {
+	mu    sync.Mutex
+	pairs map[string]KVPair
+	// deleted keeps the index at which each key was deleted, so that
+	// watchers of its prefix see the deletion
+	deleted map[string]uint64
+	index   uint64
+	changed chan struct{}
+	wait    time.Duration
+}
+
+func NewMemoryKVStore() *MemoryKVStore {
+	return &MemoryKVStore{
+		pairs:   make(map[string]KVPair),
+		deleted: make(map[string]uint64),
+		index:   1,
+		changed: make(chan struct{}),
+		wait:    5 * time.Minute,
+	}
+}
+
+func (m *MemoryKVStore) Put(key string, value string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.index++
+	m.pairs[key] = KVPair{Key: key, Value: []byte(value), ModifyIndex: m.index}
+	delete(m.deleted, key)
+	m.notify()
+}
+
+func (m *MemoryKVStore) Delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.pairs[key]; !ok {
+		return
+	}
+	m.index++
+	delete(m.pairs, key)
+	m.deleted[key] = m.index
+	m.notify()
+}
+
+func (m *MemoryKVStore) notify() {
+	close(m.changed)
+	m.changed = make(chan struct{})
+}
+
+func (m *MemoryKVStore) List(ctx context.Context, prefix string) ([]KVPair, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.list(prefix), nil
+}
+
+func (m *MemoryKVStore) list(prefix string) []KVPair {
+	var out []KVPair
+	for key, pair := range m.pairs {
+		if strings.HasPrefix(key, prefix) {
+			out = append(out, pair)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// prefixIndex returns the index of the last change under prefix, like the
+// index Consul reports for a recursive query. It is at least 1.
+func (m *MemoryKVStore) prefixIndex(prefix string) uint64 {
+	index := uint64(1)
+	for key, pair := range m.pairs {
+		if strings.HasPrefix(key, prefix) {
+			index = max(index, pair.ModifyIndex)
+		}
+	}
+	for key, deleted := range m.deleted {
+		if strings.HasPrefix(key, prefix) {
+			index = max(index, deleted)
+		}
+	}
+	return index
+}
+
+// Watch returns once a key under prefix changed past index. Changes to other
+// keys do not wake it.
+func (m *MemoryKVStore) Watch(ctx context.Context, prefix string, index uint64) ([]KVPair, uint64, error) {
+	timeout := time.NewTimer(m.wait)
+	defer timeout.Stop()
+
+	for {
+		m.mu.Lock()
+		current, changed := m.prefixIndex(prefix), m.changed
+		if current != index {
+			pairs := m.list(prefix)
+			m.mu.Unlock()
+			return pairs, current, nil
+		}
+		m.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, index, ctx.Err()
+		case <-timeout.C:
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			return m.list(prefix), index, nil
+		case <-changed:
+		}
+	}
+}
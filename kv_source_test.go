@@ -0,0 +1,153 @@
+package ascanius
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newServiceStore() *MemoryKVStore {
+	store := NewMemoryKVStore()
+	store.Put("services/api/", "")
+	store.Put("services/api/mongo/host", "kv.mongo.local")
+	store.Put("services/api/mongo/port", "27019")
+	store.Put("services/api/log/outputs", `["stdout","syslog"]`)
+	store.Put("services/api/server/tls/disabled", "true")
+	store.Put("services/other/mongo/host", "other.mongo.local")
+	return store
+}
+
+// consulAPI emulates the Consul KV endpoint on top of a MemoryKVStore.
+func consulAPI(t *testing.T, store *MemoryKVStore) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "secret-token", r.Header.Get("X-Consul-Token"))
+		require.Equal(t, "true", r.URL.Query().Get("recurse"))
+
+		prefix := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+		var (
+			pairs []KVPair
+			index uint64
+			err   error
+		)
+		if raw := r.URL.Query().Get("index"); raw != "" {
+			wait, _ := time.ParseDuration(r.URL.Query().Get("wait"))
+			requested, _ := strconv.ParseUint(raw, 10, 64)
+			ctx, cancel := context.WithTimeout(r.Context(), wait)
+			defer cancel()
+			pairs, index, err = store.Watch(ctx, prefix, requested)
+			if err != nil {
+				pairs, err = store.List(r.Context(), prefix)
+				index = requested
+			}
+		} else {
+			pairs, err = store.List(r.Context(), prefix)
+			store.mu.Lock()
+			index = store.index
+			store.mu.Unlock()
+		}
+		require.NoError(t, err)
+
+		w.Header().Set("X-Consul-Index", strconv.FormatUint(index, 10))
+		if len(pairs) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(pairs)
+	})
+}
+
+func TestKVSource(t *testing.T) {
+	store := newServiceStore()
+
+	var cfg AppConfig
+	builder := New().
+		Source("./files/mongo.json", 1).
+		AddSource(NewKVSource(store, "services/api/", "", 10)).
+		Load(&cfg)
+	require.False(t, builder.HasErrs(), builder.Errs())
+
+	require.Equal(t, "kv.mongo.local", cfg.Mongo.Host)
+	require.Equal(t, uint16(27019), cfg.Mongo.Port)
+	require.Equal(t, "toml-db", cfg.Mongo.Database)
+	require.Equal(t, []string{"stdout", "syslog"}, cfg.Log.Outputs)
+	require.True(t, cfg.Server.Tls.Disabled)
+
+	store.Put("services/api/mongo", "flat")
+	data, err := NewKVSource(store, "services/api/", "", 1).Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		PROPERTIES_VALUE_KEY: "flat",
+		"host":               "kv.mongo.local",
+		"port":               int64(27019),
+	}, data["mongo"])
+}
+
+func TestMemoryKVStoreWatchPrefix(t *testing.T) {
+	store := newServiceStore()
+	_, index, err := store.Watch(context.Background(), "services/api/", 0)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := store.Watch(ctx, "services/api/", index)
+		done <- err
+	}()
+	store.Put("services/other/mongo/host", "moved.mongo.local")
+	store.Delete("services/other/mongo/host")
+	require.ErrorIs(t, <-done, context.DeadlineExceeded)
+
+	store.Delete("services/api/mongo/port")
+	pairs, next, err := store.Watch(context.Background(), "services/api/", index)
+	require.NoError(t, err)
+	require.Greater(t, next, index)
+	require.Len(t, pairs, 4)
+}
+
+func TestConsulClient(t *testing.T) {
+	store := newServiceStore()
+	server := httptest.NewServer(consulAPI(t, store))
+	defer server.Close()
+
+	client := NewConsulClient(server.URL, "secret-token").WithWait(2 * time.Second)
+	src := NewKVSource(client, "services/api/", "consul", 10)
+
+	data, err := src.Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"mongo":  map[string]any{"host": "kv.mongo.local", "port": int64(27019)},
+		"log":    map[string]any{"outputs": []any{"stdout", "syslog"}},
+		"server": map[string]any{"tls": map[string]any{"disabled": true}},
+	}, data)
+
+	empty, err := NewKVSource(client, "services/missing/", "", 1).Load()
+	require.NoError(t, err)
+	require.Empty(t, empty)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan map[string]any, 1)
+	go src.Watch(ctx, func(data map[string]any, err error) {
+		if err == nil {
+			changes <- data
+		}
+	})
+
+	time.Sleep(100 * time.Millisecond)
+	store.Put("services/api/mongo/host", "moved.mongo.local")
+
+	select {
+	case data := <-changes:
+		require.Equal(t, "moved.mongo.local", data["mongo"].(map[string]any)["host"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change received")
+	}
+}
@@ -0,0 +1,246 @@
+package ascanius
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	"github.com/adrenaissance/ascanius/internal/atomicfile"
+)
+
+const VERSION_KEY = "version"
+
+// Migration upgrades the normalized map of a source by one version, in place.
+type Migration func(data map[string]any) error
+
+// Migrations registers the steps that upgrade sources declaring an older
+// version under the "version" key: the first step upgrades version 1 to 2,
+// the second 2 to 3, and so on. Steps run on each source after its keys are
+// normalized and before it is validated and merged. Sources without a
+// version key are not migrated.
+func (b *Builder) Migrations(steps ...Migration) *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.migrations = append(b.migrations, steps...)
+	// cached sources were migrated with the previous steps
+	clear(b.mapSource)
+	b.merged = nil
+	return b
+}
+
+// migrate runs the steps data needs to reach the latest version, len(steps)
+// + 1, and returns the version it declared. Data without a version key is
+// left alone.
+func migrate(data map[string]any, steps []Migration) (int, error) {
+	latest := len(steps) + 1
+	raw, ok := data[VERSION_KEY]
+	if !ok {
+		return latest, nil
+	}
+	version, err := toInt64(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %v: %w", VERSION_KEY, raw, err)
+	}
+	if version < 1 || version > int64(latest) {
+		return 0, fmt.Errorf("unsupported %s %d, the latest is %d", VERSION_KEY, version, latest)
+	}
+
+	for v := int(version); v < latest; v++ {
+		if err := steps[v-1](data); err != nil {
+			return 0, fmt.Errorf("migrating from version %d: %w", v, err)
+		}
+		data[VERSION_KEY] = v + 1
+	}
+	return int(version), nil
+}
+
+// MigrateFile upgrades the JSON, YAML or TOML file at path to the latest
+// version and rewrites it atomically. Only the keys the migration changed are
+// edited, with the editors used by Save, so comments, key order and the
+// spelling of other keys are kept. Files already at the latest version are
+// not touched. YAML files with several documents are not supported.
+func MigrateFile(path string, steps ...Migration) (from, to int, err error) {
+	var edit func(content []byte, e fileEdit) ([]byte, error)
+	format := normalizeFormat(filepath.Ext(path))
+	switch format {
+	case JSON_SOURCE_NAME:
+		edit = editJson
+	case YAML_SOURCE_NAME:
+		edit = editYaml
+	case TOML_SOURCE_NAME:
+		edit = editToml
+	default:
+		return 0, 0, fmt.Errorf("cannot migrate %s: unsupported format %q, only JSON, YAML and TOML files are supported", path, filepath.Ext(path))
+	}
+
+	// the file is read once, so the edits apply to the content migrated
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return 0, 0, err
+	}
+	content, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return 0, 0, err
+	}
+	if format == YAML_SOURCE_NAME {
+		if docs, err := decodeYamlDocuments(content); err == nil && len(docs) > 1 {
+			return 0, 0, fmt.Errorf("cannot migrate %s: files with several YAML documents are not supported", path)
+		}
+	}
+	loaded, err := decodeFormat(format, content, "")
+	if err != nil {
+		return 0, 0, newParseError(path, content, err)
+	}
+
+	original := normalizeKeysToSnakeCase(loaded)
+	data := deepCopyMap(original)
+	to = len(steps) + 1
+	if from, err = migrate(data, steps); err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", path, err)
+	}
+	if from == to {
+		return from, to, nil
+	}
+
+	edits, err := migrationEdits(original, data, "")
+	if err != nil {
+		return 0, 0, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, e := range edits {
+		if content, err = edit(content, e); err != nil {
+			return 0, 0, fmt.Errorf("%s: cannot update %s: %w", path, e.key, err)
+		}
+	}
+	return from, to, atomicfile.WriteFile(path, content, info.Mode().Perm())
+}
+
+// migrationEdits returns the edits turning before into after: removals
+// first, then values set leaf by leaf.
+func migrationEdits(before, after map[string]any, parent string) ([]fileEdit, error) {
+	var removed, set []fileEdit
+	for _, k := range sortedMapKeys(before) {
+		key := joinKey(parent, k)
+		old := before[k]
+		value, ok := after[k]
+		oldMap, oldIsMap := old.(map[string]any)
+		newMap, newIsMap := value.(map[string]any)
+		switch {
+		case !ok:
+			removed = appendRemovals(removed, key, old)
+		case oldIsMap && newIsMap:
+			edits, err := migrationEdits(oldMap, newMap, key)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range edits {
+				if e.remove {
+					removed = append(removed, e)
+				} else {
+					set = append(set, e)
+				}
+			}
+		case reflect.DeepEqual(old, value):
+		default:
+			if oldIsMap || newIsMap {
+				// a section replaced by a value, or the other way around
+				removed = appendRemovals(removed, key, old)
+			}
+			writable, err := writableValue(value)
+			if err != nil {
+				return nil, fmt.Errorf("cannot set %s: %w", key, err)
+			}
+			set = appendEdits(set, key, writable)
+		}
+	}
+	for _, k := range sortedMapKeys(after) {
+		if _, ok := before[k]; ok {
+			continue
+		}
+		key := joinKey(parent, k)
+		writable, err := writableValue(after[k])
+		if err != nil {
+			return nil, fmt.Errorf("cannot set %s: %w", key, err)
+		}
+		set = appendEdits(set, key, writable)
+	}
+	return append(removed, set...), nil
+}
+
+// appendRemovals records the removal of every leaf of value at key.
+func appendRemovals(edits []fileEdit, key string, value any) []fileEdit {
+	m, ok := value.(map[string]any)
+	if !ok || len(m) == 0 {
+		return append(edits, fileEdit{key: key, remove: true})
+	}
+	for _, k := range sortedMapKeys(m) {
+		edits = appendRemovals(edits, joinKey(key, k), m[k])
+	}
+	return edits
+}
+
+func sortedMapKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// MoveKey returns a Migration moving the value at the dotted key path from
+// to the key path to. It fails when both keys are set.
+func MoveKey(from, to string) Migration {
+	from, to = normalizeKeyPath(from), normalizeKeyPath(to)
+	return func(data map[string]any) error {
+		value, ok := lookupPath(data, from)
+		if !ok {
+			return nil
+		}
+		if _, ok := lookupPath(data, to); ok {
+			return fmt.Errorf("cannot move %s to %s: %s is already set", from, to, to)
+		}
+		deletePath(data, from)
+		setPath(data, to, value)
+		return nil
+	}
+}
+
+// DeleteKey returns a Migration removing the given dotted key paths.
+func DeleteKey(keys ...string) Migration {
+	return func(data map[string]any) error {
+		for _, key := range keys {
+			deletePath(data, normalizeKeyPath(key))
+		}
+		return nil
+	}
+}
+
+// SetKey returns a Migration setting the dotted key path key to value.
+func SetKey(key string, value any) Migration {
+	key = normalizeKeyPath(key)
+	return func(data map[string]any) error {
+		setPath(data, key, deepCopyValue(value))
+		return nil
+	}
+}
+
+// Steps combines migrations into one step, run in order.
+func Steps(migrations ...Migration) Migration {
+	return func(data map[string]any) error {
+		for _, m := range migrations {
+			if err := m(data); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
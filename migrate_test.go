@@ -0,0 +1,151 @@
+package ascanius
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testMigrations = []Migration{
+	// v1 -> v2: mongo settings move to their own section
+	Steps(MoveKey("mongo_host", "mongo.host"), MoveKey("mongo_port", "mongo.port")),
+	// v2 -> v3: the legacy flag is gone, tls is on by default
+	Steps(DeleteKey("legacy"), SetKey("mongo.tls", true)),
+}
+
+func TestMigrations(t *testing.T) {
+	type mongo struct {
+		Host string
+		Port int
+		Tls  bool
+	}
+	type config struct {
+		Version int
+		Mongo   mongo
+	}
+
+	b := New().
+		AddSource(&stubSource{name: "v1", priority: 1, data: map[string]any{"version": 1, "mongo_host": "old", "mongo_port": 27017, "legacy": true}}).
+		AddSource(&stubSource{name: "v2", priority: 2, data: map[string]any{"version": 2, "mongo": map[string]any{"host": "new"}}}).
+		AddSource(&stubSource{name: "unversioned", priority: 3, data: map[string]any{"mongo_host": "kept"}}).
+		Migrations(testMigrations...)
+
+	var cfg config
+	b.Load(&cfg)
+	require.False(t, b.HasErrs(), b.Errs())
+	assert.Equal(t, config{Version: 3, Mongo: mongo{Host: "new", Port: 27017, Tls: true}}, cfg)
+
+	_, ok := b.Lookup("legacy")
+	assert.False(t, ok)
+	value, _ := b.Lookup("mongo_host")
+	assert.Equal(t, "kept", value, "sources without a version are not migrated")
+}
+
+func TestMigrationErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data map[string]any
+		msg  string
+	}{
+		{"newer version", map[string]any{"version": 4}, "source stub: unsupported version 4, the latest is 3"},
+		{"invalid version", map[string]any{"version": "two"}, "source stub: invalid version two"},
+		{"failing step", map[string]any{"version": 1, "mongo_host": "a", "mongo": map[string]any{"host": "b"}}, "source stub: migrating from version 1: cannot move mongo_host to mongo.host: mongo.host is already set"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New().
+				AddSource(&stubSource{name: "stub", priority: 1, data: tt.data}).
+				Migrations(testMigrations...)
+			b.Merged()
+
+			var serr *SourceError
+			require.ErrorAs(t, b.Err(), &serr)
+			assert.Contains(t, serr.Error(), tt.msg)
+		})
+	}
+}
+
+func TestMigrateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("version: 1\nmongoHost: db\nlegacy: true\n"), 0o640))
+
+	from, to, err := MigrateFile(path, testMigrations...)
+	require.NoError(t, err)
+	assert.Equal(t, 1, from)
+	assert.Equal(t, 3, to)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "version: 3\nmongo:\n  host: db\n  tls: true\n", string(content))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+
+	from, to, err = MigrateFile(path, testMigrations...)
+	require.NoError(t, err)
+	assert.Equal(t, 3, from)
+	assert.Equal(t, 3, to)
+
+	for _, name := range []string{".env", "app.ini", "app.properties", "app.jsonc"} {
+		_, _, err = MigrateFile(writeTemp(t, name, "version = 1\n"), testMigrations...)
+		assert.ErrorContains(t, err, "unsupported format", name)
+	}
+}
+
+func TestMigrateFileKeepsFormatting(t *testing.T) {
+	yamlPath := writeTemp(t, "config.yaml", `# service configuration
+version: 1 # bumped by migrations
+
+# connection
+mongoHost: db
+legacy: true # to be removed
+logLevel: info
+`)
+	_, _, err := MigrateFile(yamlPath, testMigrations...)
+	require.NoError(t, err)
+	assert.Equal(t, `# service configuration
+version: 3 # bumped by migrations
+logLevel: info
+mongo:
+  host: db
+  tls: true
+`, readFile(t, yamlPath))
+
+	tomlPath := writeTemp(t, "config.toml", `# service configuration
+version = 1
+mongoHost = "db" # moved to [mongo]
+logLevel = "info"
+
+[legacy_section]
+legacy = true
+`)
+	_, _, err = MigrateFile(tomlPath, MoveKey("mongo_host", "mongo.host"), DeleteKey("legacy_section.legacy"))
+	require.NoError(t, err)
+	assert.Equal(t, `# service configuration
+version = 3
+logLevel = "info"
+
+[mongo]
+host = "db"
+`, readFile(t, tomlPath))
+
+	jsonPath := writeTemp(t, "config.json", `{
+    "version": 1,
+    "logLevel": "info",
+    "mongoHost": "db"
+}
+`)
+	_, _, err = MigrateFile(jsonPath, MoveKey("mongo_host", "mongo.host"))
+	require.NoError(t, err)
+	assert.Equal(t, `{
+    "version": 2,
+    "logLevel": "info",
+    "mongo": {
+        "host": "db"
+    }
+}
+`, readFile(t, jsonPath))
+}
@@ -0,0 +1,210 @@
+package ascanius
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const PROPERTIES_SOURCE_NAME = "properties"
+
+// PROPERTIES_VALUE_KEY holds the value of a key that also has nested keys,
+// such as log4j.rootLogger next to log4j.rootLogger.appender.
+const PROPERTIES_VALUE_KEY = "_value"
+
+// PropertiesSource reads Java .properties files. Dotted keys such as
+// db.pool.size are expanded into nested maps, and values get the scalar
+// inference of .env files. A key that also has nested keys keeps its value
+// under PROPERTIES_VALUE_KEY. Lines ending with a backslash continue on the
+// next one, and \uXXXX escapes are decoded. The source is read-only.
+type PropertiesSource struct {
+	name     string
+	path     string
+	priority int
+}
+
+func NewPropertiesSource(path string, name string, priority int) *PropertiesSource {
+	if name == "" {
+		name = path
+	}
+	return &PropertiesSource{
+		name:     name,
+		path:     path,
+		priority: priority,
+	}
+}
+
+func (s *PropertiesSource) Load() (map[string]any, error) {
+	bytes, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := decodeProperties(bytes)
+	if err != nil {
+		return nil, newParseError(s.path, bytes, err)
+	}
+	return result, nil
+}
+
+func (s *PropertiesSource) Name() string {
+	return s.name
+}
+
+func (s *PropertiesSource) SetName(name string) {
+	s.name = name
+}
+
+func (s *PropertiesSource) Priority() int {
+	return s.priority
+}
+
+func (s *PropertiesSource) SetPriority(p int) {
+	s.priority = p
+}
+
+func (s *PropertiesSource) Type() string {
+	return PROPERTIES_SOURCE_NAME
+}
+
+const propertiesSpace = " \t\f"
+
+func decodeProperties(content []byte) (map[string]any, error) {
+	root := make(map[string]any)
+
+	lines := strings.Split(strings.TrimPrefix(string(content), "\uFEFF"), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimLeft(strings.TrimSuffix(lines[i], "\r"), propertiesSpace)
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		for continues(line) {
+			line = line[:len(line)-1]
+			if i+1 == len(lines) {
+				break
+			}
+			i++
+			line += strings.TrimLeft(strings.TrimSuffix(lines[i], "\r"), propertiesSpace)
+		}
+
+		// the key ends at the first unescaped separator or white space
+		end := 0
+		for ; end < len(line) && !strings.ContainsRune("=:"+propertiesSpace, rune(line[end])); end++ {
+			if line[end] == '\\' {
+				end++
+			}
+		}
+		end = min(end, len(line))
+		rest := strings.TrimLeft(line[end:], propertiesSpace)
+		if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+			rest = strings.TrimLeft(rest[1:], propertiesSpace)
+		}
+
+		key, err := unescapeProperties(line[:end])
+		if err != nil {
+			return nil, &lineError{line: lineNo, msg: err.Error()}
+		}
+		value, err := unescapeProperties(rest)
+		if err != nil {
+			return nil, &lineError{line: lineNo, msg: fmt.Sprintf("%s: %v", key, err)}
+		}
+		parts, ok := splitDottedKey(key)
+		if !ok {
+			return nil, &lineError{line: lineNo, msg: fmt.Sprintf("invalid key %q", key)}
+		}
+		setProperty(root, parts, inferValue(value))
+	}
+	return root, nil
+}
+
+// setProperty sets the value at path in root like setDotted, except that a
+// key holding both a value and nested keys becomes a map with the value
+// under PROPERTIES_VALUE_KEY, whichever comes first in the file.
+func setProperty(root map[string]any, path []string, value any) {
+	current := root
+	for _, part := range path[:len(path)-1] {
+		next, isMap := current[part].(map[string]any)
+		if !isMap {
+			next = make(map[string]any)
+			if existing, exists := current[part]; exists {
+				next[PROPERTIES_VALUE_KEY] = existing
+			}
+			current[part] = next
+		}
+		current = next
+	}
+	last := path[len(path)-1]
+	if section, isMap := current[last].(map[string]any); isMap {
+		section[PROPERTIES_VALUE_KEY] = value
+		return
+	}
+	current[last] = value
+}
+
+// continues reports whether line ends with an odd number of backslashes.
+func continues(line string) bool {
+	n := len(line) - len(strings.TrimRight(line, `\`))
+	return n%2 == 1
+}
+
+// unescapeProperties decodes the escapes of .properties files: \t, \n, \r,
+// \f and \uXXXX, with UTF-16 surrogate pairs. A backslash before any other
+// character is dropped.
+func unescapeProperties(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			sb.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			sb.WriteByte('\t')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'u':
+			r, err := unicodeEscape(s, i+1)
+			if err != nil {
+				return "", err
+			}
+			i += 4
+			if utf16.IsSurrogate(r) && strings.HasPrefix(s[i+1:], `\u`) {
+				if low, err := unicodeEscape(s, i+3); err == nil {
+					if pair := utf16.DecodeRune(r, low); pair != utf8.RuneError {
+						r = pair
+						i += 6
+					}
+				}
+			}
+			sb.WriteRune(r)
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String(), nil
+}
+
+// unicodeEscape parses the four hex digits of a \u escape at s[start:].
+func unicodeEscape(s string, start int) (rune, error) {
+	if start+4 > len(s) {
+		return 0, fmt.Errorf("malformed \\u escape %q", s[start-2:])
+	}
+	code, err := strconv.ParseUint(s[start:start+4], 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("malformed \\u escape %q", s[start-2:start+4])
+	}
+	return rune(code), nil
+}
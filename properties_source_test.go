@@ -0,0 +1,93 @@
+package ascanius
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPropertiesSource(t *testing.T) {
+	path := writeTemp(t, "app.properties", `# generated by the legacy service
+! another comment
+app.name = billing
+db.pool.size=10
+db.pool.enabled:true
+db.url   jdbc:postgresql://localhost/billing
+greeting = caf\u00e9 \uD83D\uDE00
+path = C:\\data\\billing
+key\ with\ spaces = spaced
+hosts = a,\
+        b,\
+        c
+empty =
+`)
+	data, err := NewPropertiesSource(path, "", 1).Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"app": map[string]any{"name": "billing"},
+		"db": map[string]any{
+			"pool": map[string]any{"size": int64(10), "enabled": true},
+			"url":  "jdbc:postgresql://localhost/billing",
+		},
+		"greeting":        "café 😀",
+		"path":            `C:\data\billing`,
+		"key with spaces": "spaced",
+		"hosts":           "a,b,c",
+		"empty":           "",
+	}, data)
+
+	var cfg struct {
+		Db struct {
+			Pool struct {
+				Size int
+			}
+		}
+	}
+	require.NoError(t, New().Source(path, 1).Load(&cfg).Err())
+	assert.Equal(t, 10, cfg.Db.Pool.Size)
+}
+
+func TestPropertiesErrors(t *testing.T) {
+	var perr *ParseError
+
+	_, err := NewPropertiesSource(writeTemp(t, "bad.properties", "a=1\nb=\\u12G4\n"), "", 1).Load()
+	require.ErrorAs(t, err, &perr)
+	assert.Equal(t, 2, perr.Line)
+	assert.Contains(t, err.Error(), "malformed \\u escape")
+}
+
+func TestPropertiesValueAndSection(t *testing.T) {
+	path := writeTemp(t, "log4j.properties", `log4j.appender.stdout.layout.ConversionPattern=%d %m%n
+log4j.rootLogger=INFO, stdout
+log4j.rootLogger.appender=stdout
+log4j.appender.stdout=org.apache.log4j.ConsoleAppender
+log4j.appender.stdout.layout=org.apache.log4j.PatternLayout
+`)
+	data, err := NewPropertiesSource(path, "", 1).Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"rootLogger": map[string]any{PROPERTIES_VALUE_KEY: "INFO, stdout", "appender": "stdout"},
+		"appender": map[string]any{
+			"stdout": map[string]any{
+				PROPERTIES_VALUE_KEY: "org.apache.log4j.ConsoleAppender",
+				"layout": map[string]any{
+					PROPERTIES_VALUE_KEY: "org.apache.log4j.PatternLayout",
+					"ConversionPattern":  "%d %m%n",
+				},
+			},
+		},
+	}, data["log4j"])
+
+	var cfg struct {
+		Log4j struct {
+			RootLogger struct {
+				Level    string `cfg:"_value"`
+				Appender string
+			}
+		}
+	}
+	require.NoError(t, New().Source(path, 1).Load(&cfg).Err())
+	assert.Equal(t, "INFO, stdout", cfg.Log4j.RootLogger.Level)
+	assert.Equal(t, "stdout", cfg.Log4j.RootLogger.Appender)
+}
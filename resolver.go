@@ -0,0 +1,204 @@
+package ascanius
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	SECRET_REF_PREFIX      = "ref+"
+	DEFAULT_SECRET_TIMEOUT = 10 * time.Second
+)
+
+// SecretResolver resolves references such as "ref+file:///run/secrets/db"
+// to their value. ref is everything after "<scheme>://".
+type SecretResolver interface {
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+// ResolverFunc adapts a function to a SecretResolver.
+type ResolverFunc func(ctx context.Context, ref string) (string, error)
+
+func (f ResolverFunc) Resolve(ctx context.Context, ref string) (string, error) {
+	return f(ctx, ref)
+}
+
+// FileResolver reads the referenced file, e.g. "ref+file:///run/secrets/db".
+// Trailing newlines are removed.
+type FileResolver struct{}
+
+func (FileResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	raw, err := os.ReadFile(ref)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(raw), "\r\n"), nil
+}
+
+// EnvResolver reads the referenced environment variable, e.g. "ref+env://DB_PASSWORD".
+type EnvResolver struct{}
+
+func (EnvResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	value, ok := os.LookupEnv(ref)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", ref)
+	}
+	return value, nil
+}
+
+// ExecResolver runs the referenced command without a shell and returns its
+// trimmed standard output, e.g. "ref+exec://pass show db". It is not
+// registered by default.
+type ExecResolver struct{}
+
+func (ExecResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	args := strings.Fields(ref)
+	if len(args) == 0 {
+		return "", errors.New("empty command")
+	}
+	var stderr strings.Builder
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// Resolver registers r for "ref+<scheme>://" references, replacing any
+// resolver previously registered for scheme. The file and env schemes are
+// registered by default.
+func (b *Builder) Resolver(scheme string, r SecretResolver) *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.resolvers[strings.ToLower(scheme)] = r
+	return b
+}
+
+// KeepReferences leaves "ref+<scheme>://" references as they are instead of
+// resolving them, e.g. to convert or print a config without reading the
+// secrets it points to.
+func (b *Builder) KeepReferences() *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.keepRefs = true
+	return b
+}
+
+// SecretTimeout sets how long a single reference may take to resolve. Zero
+// means no limit, like LoadTimeout.
+func (b *Builder) SecretTimeout(d time.Duration) *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.secretTimeout = d
+	return b
+}
+
+// IsSecretRef reports whether s is a secret reference.
+func IsSecretRef(s string) bool {
+	return strings.HasPrefix(s, SECRET_REF_PREFIX) && strings.Contains(s, "://")
+}
+
+type refCache map[string]string
+
+func (b *Builder) resolveRefs(ctx context.Context, data map[string]any, parent string, cache refCache) {
+	b.resolveMap(ctx, data, parent, parent, cache)
+}
+
+// resolveMap resolves the references in data. key is the path secrets are
+// recorded under, shown the path with list indexes used in errors.
+func (b *Builder) resolveMap(ctx context.Context, data map[string]any, key, shown string, cache refCache) {
+	for k, v := range data {
+		childKey, childShown := joinKey(key, k), joinKey(shown, k)
+		switch val := v.(type) {
+		case map[string]any:
+			b.resolveMap(ctx, val, childKey, childShown, cache)
+		case []any:
+			b.resolveList(ctx, val, childKey, childShown, cache)
+		case string:
+			if !IsSecretRef(val) {
+				continue
+			}
+			if resolved := b.resolveRef(ctx, childKey, childShown, val, cache); resolved != nil {
+				data[k] = resolved
+			} else {
+				delete(data, k)
+			}
+		}
+	}
+}
+
+func (b *Builder) resolveList(ctx context.Context, list []any, key, shown string, cache refCache) {
+	for i, item := range list {
+		indexed := fmt.Sprintf("%s[%d]", shown, i)
+		switch val := item.(type) {
+		case map[string]any:
+			b.resolveMap(ctx, val, key, indexed, cache)
+		case []any:
+			b.resolveList(ctx, val, key, indexed, cache)
+		case string:
+			if IsSecretRef(val) {
+				list[i] = b.resolveRef(ctx, key, indexed, val, cache)
+			}
+		}
+	}
+}
+
+func (b *Builder) resolveRef(ctx context.Context, key, shown, ref string, cache refCache) any {
+	b.secretPaths[key] = true
+	if value, ok := cache[ref]; ok {
+		return value
+	}
+
+	scheme, target, _ := strings.Cut(strings.TrimPrefix(ref, SECRET_REF_PREFIX), "://")
+	resolver, ok := b.resolvers[strings.ToLower(scheme)]
+	if !ok {
+		b.errs = append(b.errs, newSecretError(shown, ref, fmt.Errorf("no resolver for scheme %s", scheme)))
+		return nil
+	}
+
+	value, err := resolveWithTimeout(ctx, resolver, target, b.secretTimeout)
+	if err != nil {
+		b.errs = append(b.errs, newSecretError(shown, ref, err))
+		return nil
+	}
+
+	cache[ref] = value
+	return value
+}
+
+// resolveWithTimeout stops waiting once the timeout expires, even when the
+// resolver does not honor ctx. A timeout of zero or less means no limit.
+func resolveWithTimeout(ctx context.Context, r SecretResolver, ref string, timeout time.Duration) (string, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	type result struct {
+		value string
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		value, err := r.Resolve(ctx, ref)
+		done <- result{value, err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.value, res.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
@@ -0,0 +1,180 @@
+package ascanius
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// httpResolver is a stand-in for a remote vault: "ref+vault://<path>" is
+// fetched from a local HTTP server.
+type httpResolver struct {
+	url   string
+	calls atomic.Int32
+}
+
+func (r *httpResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	r.calls.Add(1)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url+"/"+ref, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New(resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
+
+func TestSecretRefs(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "mongo")
+	require.NoError(t, os.WriteFile(secretFile, []byte("file-pass\n"), 0o600))
+	t.Setenv("MONGO_USER", "env-user")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/kv/api" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("vault-token"))
+	}))
+	defer server.Close()
+	vault := &httpResolver{url: server.URL}
+
+	config := filepath.Join(dir, "config.json")
+	require.NoError(t, os.WriteFile(config, []byte(`{
+		"mongo": {
+			"password": "ref+file://`+secretFile+`",
+			"user": "ref+env://MONGO_USER",
+			"host": "ref+exec://echo mongo.local"
+		},
+		"api": {"key": "ref+vault://kv/api", "backup_key": "ref+vault://kv/api"}
+	}`), 0o644))
+
+	var cfg struct {
+		Mongo struct {
+			Password string
+			User     string
+			Host     string
+		}
+		Api struct {
+			Key       string
+			BackupKey string
+		}
+	}
+	builder := New().
+		Resolver("exec", ExecResolver{}).
+		Resolver("vault", vault).
+		Source(config, 1).
+		Load(&cfg)
+	require.False(t, builder.HasErrs(), builder.Errs())
+
+	require.Equal(t, "file-pass", cfg.Mongo.Password)
+	require.Equal(t, "env-user", cfg.Mongo.User)
+	require.Equal(t, "mongo.local", cfg.Mongo.Host)
+	require.Equal(t, "vault-token", cfg.Api.Key)
+	require.Equal(t, "vault-token", cfg.Api.BackupKey)
+	require.Equal(t, int32(1), vault.calls.Load())
+
+	var out bytes.Buffer
+	require.NoError(t, builder.Export(&out, "json"))
+	for _, secret := range []string{"file-pass", "env-user", "vault-token"} {
+		require.NotContains(t, out.String(), secret)
+	}
+}
+
+func TestSecretRefErrors(t *testing.T) {
+	t.Setenv("APP__DB__PASSWORD", "ref+env://MISSING_SECRET")
+	t.Setenv("APP__DB__TOKEN", "ref+slow://token")
+	t.Setenv("APP__DB__KEY", "ref+unknown://key")
+
+	blocked := make(chan struct{})
+	defer close(blocked)
+
+	var cfg struct {
+		Db struct {
+			Password string
+			Token    string
+			Key      string `def:"fallback"`
+		}
+	}
+	start := time.Now()
+	builder := New().
+		Resolver("slow", ResolverFunc(func(ctx context.Context, ref string) (string, error) {
+			<-blocked
+			return "too late", nil
+		})).
+		SecretTimeout(50*time.Millisecond).
+		Source("env", 1).
+		Load(&cfg)
+	require.Less(t, time.Since(start), 5*time.Second)
+
+	var msgs []string
+	for _, err := range builder.Errs() {
+		msgs = append(msgs, err.Error())
+	}
+	all := strings.Join(msgs, "\n")
+	require.Len(t, msgs, 3)
+	require.Contains(t, all, "cannot resolve db.password (ref+env://MISSING_SECRET): environment variable MISSING_SECRET is not set")
+	require.Contains(t, all, "cannot resolve db.token (ref+slow://token): context deadline exceeded")
+	require.Contains(t, all, "cannot resolve db.key (ref+unknown://key): no resolver for scheme unknown")
+	require.Equal(t, "fallback", cfg.Db.Key)
+}
+
+func TestSecretRefErrorPaths(t *testing.T) {
+	path := writeTemp(t, "config.yaml", `servers:
+  - host: a
+  - host: b
+    password: ref+env://MISSING_SECRET
+tokens: [plain, ref+env://MISSING_SECRET]
+`)
+	var cfg struct{}
+	builder := New().Source(path, 1).Load(&cfg)
+	require.Len(t, builder.Errs(), 2)
+
+	var keys []string
+	for _, err := range builder.Errs() {
+		var serr *SecretError
+		require.ErrorAs(t, err, &serr)
+		require.Equal(t, "ref+env://MISSING_SECRET", serr.Ref)
+		keys = append(keys, fmt.Sprintf("%s %d", serr.Key, serr.Index))
+	}
+	require.ElementsMatch(t, []string{"servers[1].password 1", "tokens[1] 1"}, keys)
+	require.Contains(t, errorReport(builder.Errs()), "merged configuration:")
+}
+
+func TestSecretTimeoutZero(t *testing.T) {
+	t.Setenv("APP__TOKEN", "ref+slow://token")
+
+	var cfg struct {
+		Token string
+	}
+	builder := New().
+		Resolver("slow", ResolverFunc(func(ctx context.Context, ref string) (string, error) {
+			time.Sleep(10 * time.Millisecond)
+			return "late but fine", ctx.Err()
+		})).
+		SecretTimeout(0).
+		Source("env", 1).
+		Load(&cfg)
+	require.False(t, builder.HasErrs(), builder.Errs())
+	require.Equal(t, "late but fine", cfg.Token)
+}
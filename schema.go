@@ -0,0 +1,339 @@
+package ascanius
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const JSON_SCHEMA_DRAFT = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema is the subset of JSON Schema (draft 2020-12) that can be derived
+// from a config struct.
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"`
+	Default              any                    `json:"default,omitempty"`
+	Enum                 []any                  `json:"enum,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+	MinLength            *int                   `json:"minLength,omitempty"`
+	MaxLength            *int                   `json:"maxLength,omitempty"`
+	MinItems             *int                   `json:"minItems,omitempty"`
+	MaxItems             *int                   `json:"maxItems,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	WriteOnly            bool                   `json:"writeOnly,omitempty"`
+	Defs                 map[string]*JSONSchema `json:"$defs,omitempty"`
+}
+
+// GenerateSchema builds a JSON Schema describing the configuration files that
+// Load accepts for target. Fields are resolved like Load resolves them and
+// the following tags are used:
+//
+//	cfg          property name (snake_case field name otherwise)
+//	def          default value
+//	description  property description
+//	required     "true" when the key must be present
+//	enum         comma separated list of allowed values
+//	min, max     bounds for numbers, lengths for strings and slices
+//	pattern      regular expression strings must match
+//
+// Durations and encoding.TextUnmarshaler types such as time.Time are
+// described as strings. Named structs that contain themselves are described
+// once under $defs and referenced with $ref.
+func GenerateSchema(target any) (*JSONSchema, error) {
+	if target == nil {
+		return nil, errors.New("target cannot be nil")
+	}
+	typ := reflect.TypeOf(target)
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, errors.New("target must be a struct or pointer to a struct")
+	}
+
+	gen := &schemaGen{
+		root:      typ,
+		visiting:  make(map[reflect.Type]bool),
+		recursive: make(map[reflect.Type]bool),
+		names:     make(map[reflect.Type]string),
+		taken:     make(map[string]bool),
+	}
+	schema, err := gen.structSchema(typ)
+	if err != nil {
+		return nil, err
+	}
+	schema.Schema = JSON_SCHEMA_DRAFT
+	schema.Title = typ.Name()
+	schema.Defs = gen.defs
+	return schema, nil
+}
+
+// schemaGen tracks the structs being described so that recursive types end
+// in a $ref instead of recursing forever.
+type schemaGen struct {
+	root      reflect.Type
+	visiting  map[reflect.Type]bool
+	recursive map[reflect.Type]bool
+	defs      map[string]*JSONSchema
+	// names holds the $defs key of each type, taken the keys in use
+	names map[reflect.Type]string
+	taken map[string]bool
+}
+
+func (g *schemaGen) ref(typ reflect.Type) *JSONSchema {
+	if typ == g.root {
+		return &JSONSchema{Ref: "#"}
+	}
+	return &JSONSchema{Ref: "#/$defs/" + g.defName(typ)}
+}
+
+// defName returns the $defs key of typ: its name, qualified with its package
+// path when another type already uses the name, e.g. two Node types from
+// different packages, and numbered as a last resort.
+func (g *schemaGen) defName(typ reflect.Type) string {
+	if name, ok := g.names[typ]; ok {
+		return name
+	}
+	name := defKey(typ.Name())
+	if g.taken[name] {
+		name = defKey(typ.PkgPath() + "." + typ.Name())
+	}
+	for base, i := name, 2; g.taken[name]; i++ {
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+	g.names[typ], g.taken[name] = name, true
+	return name
+}
+
+// defKey keeps the characters of name that need no escaping in a $ref.
+func defKey(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+func (g *schemaGen) structSchema(typ reflect.Type) (*JSONSchema, error) {
+	if g.visiting[typ] {
+		g.recursive[typ] = true
+		return g.ref(typ), nil
+	}
+	g.visiting[typ] = true
+	defer delete(g.visiting, typ)
+
+	schema := &JSONSchema{
+		Type:       "object",
+		Properties: make(map[string]*JSONSchema),
+	}
+
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		key := field.Tag.Get("cfg")
+		if key == "" {
+			key = toSnakeCase(field.Name)
+		}
+
+		fieldType := field.Type
+		secret := isSecretField(field)
+		if secret && reflect.PointerTo(fieldType).Implements(secretHolderType) {
+			fieldType = reflect.New(fieldType).Interface().(secretHolder).secretValue().Type()
+		}
+
+		prop, err := g.typeSchema(fieldType)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		prop.WriteOnly = secret
+		prop.Description = field.Tag.Get("description")
+
+		if err := applySchemaTags(prop, field, fieldType); err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		if field.Tag.Get("required") == "true" {
+			schema.Required = append(schema.Required, key)
+		}
+
+		schema.Properties[key] = prop
+	}
+
+	// anonymous structs cannot refer to themselves, so they stay inline
+	if g.recursive[typ] && typ != g.root && typ.Name() != "" {
+		if g.defs == nil {
+			g.defs = make(map[string]*JSONSchema)
+		}
+		g.defs[g.defName(typ)] = schema
+		return g.ref(typ), nil
+	}
+	return schema, nil
+}
+
+func (g *schemaGen) typeSchema(typ reflect.Type) (*JSONSchema, error) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if typ == timeType {
+		return &JSONSchema{Type: "string", Format: "date-time"}, nil
+	}
+	if isTextType(typ) {
+		return &JSONSchema{Type: "string"}, nil
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		return g.structSchema(typ)
+
+	case reflect.String:
+		return &JSONSchema{Type: "string"}, nil
+
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &JSONSchema{Type: "integer"}, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		zero := 0.0
+		return &JSONSchema{Type: "integer", Minimum: &zero}, nil
+
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}, nil
+
+	case reflect.Slice, reflect.Array:
+		items, err := g.typeSchema(typ.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &JSONSchema{Type: "array", Items: items}, nil
+
+	case reflect.Map:
+		if typ.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s", typ.Key())
+		}
+		values, err := g.typeSchema(typ.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &JSONSchema{Type: "object", AdditionalProperties: values}, nil
+
+	case reflect.Interface:
+		return &JSONSchema{}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported kind: %s", typ.Kind())
+	}
+}
+
+// isTextType reports whether values of typ are written as strings in config
+// files even though typ is not a string kind.
+func isTextType(typ reflect.Type) bool {
+	return typ == durationType || typ.Kind() != reflect.String && reflect.PointerTo(typ).Implements(textUnmarshalerType)
+}
+
+// textDefault returns the text form of v, the parsed def tag of a text
+// type. Durations given in nanoseconds are written like "5s".
+func textDefault(v reflect.Value, def string) string {
+	if d, ok := v.Interface().(time.Duration); ok {
+		return d.String()
+	}
+	return def
+}
+
+func applySchemaTags(prop *JSONSchema, field reflect.StructField, typ reflect.Type) error {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if isTextType(typ) {
+		// defaults are written in their text form, and bounds do not apply
+		// to it
+		if def := field.Tag.Get("def"); def != "" {
+			if v, err := parseDefault(def, typ); err == nil {
+				prop.Default = textDefault(v, def)
+			}
+		}
+		if enum := field.Tag.Get("enum"); enum != "" {
+			for _, raw := range strings.Split(enum, ",") {
+				prop.Enum = append(prop.Enum, strings.TrimSpace(raw))
+			}
+		}
+		prop.Pattern = field.Tag.Get("pattern")
+		return nil
+	}
+
+	if def := field.Tag.Get("def"); def != "" {
+		if v, err := parseDefault(def, typ); err == nil {
+			prop.Default = v.Interface()
+		}
+	}
+
+	if enum := field.Tag.Get("enum"); enum != "" {
+		for _, raw := range strings.Split(enum, ",") {
+			v, err := parseDefault(strings.TrimSpace(raw), typ)
+			if err != nil {
+				return fmt.Errorf("invalid enum value %q: %w", raw, err)
+			}
+			prop.Enum = append(prop.Enum, v.Interface())
+		}
+	}
+
+	if pattern := field.Tag.Get("pattern"); pattern != "" {
+		prop.Pattern = pattern
+	}
+
+	for _, name := range []string{"min", "max"} {
+		raw := field.Tag.Get(name)
+		if raw == "" {
+			continue
+		}
+		bound, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s tag %q: %w", name, raw, err)
+		}
+		length := int(bound)
+
+		switch prop.Type {
+		case "integer", "number":
+			if name == "min" {
+				prop.Minimum = &bound
+			} else {
+				prop.Maximum = &bound
+			}
+		case "string":
+			if name == "min" {
+				prop.MinLength = &length
+			} else {
+				prop.MaxLength = &length
+			}
+		case "array":
+			if name == "min" {
+				prop.MinItems = &length
+			} else {
+				prop.MaxItems = &length
+			}
+		default:
+			return fmt.Errorf("%s tag is not supported on %s", name, typ)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package ascanius
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSchema(t *testing.T) {
+	type Config struct {
+		Log    LogConfig
+		Server ServerConfig `description:"HTTP server settings"`
+		Mode   string       `def:"dev" enum:"dev,prod" required:"true"`
+		Token  Secret[string]
+		Labels map[string]string
+		Ratio  float64 `min:"0" max:"1"`
+	}
+
+	schema, err := GenerateSchema(&Config{})
+	require.NoError(t, err)
+
+	raw, err := json.Marshal(schema)
+	require.NoError(t, err)
+
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(raw, &doc))
+
+	require.Equal(t, JSON_SCHEMA_DRAFT, doc["$schema"])
+	require.Equal(t, "Config", doc["title"])
+	require.Equal(t, []any{"mode"}, doc["required"])
+
+	props := doc["properties"].(map[string]any)
+	require.Equal(t, map[string]any{
+		"type":    "string",
+		"default": "dev",
+		"enum":    []any{"dev", "prod"},
+	}, props["mode"])
+	require.Equal(t, map[string]any{"type": "string", "writeOnly": true}, props["token"])
+	require.Equal(t, map[string]any{"type": "number", "minimum": 0.0, "maximum": 1.0}, props["ratio"])
+	require.Equal(t, "object", props["labels"].(map[string]any)["type"])
+
+	server := props["server"].(map[string]any)
+	require.Equal(t, "HTTP server settings", server["description"])
+	serverProps := server["properties"].(map[string]any)
+	require.Equal(t, map[string]any{"type": "integer", "minimum": 0.0, "default": 8080.0}, serverProps["http_port"])
+	require.Contains(t, serverProps["tls"].(map[string]any)["properties"], "enable_mtls")
+
+	outputs := props["log"].(map[string]any)["properties"].(map[string]any)["outputs"].(map[string]any)
+	require.Equal(t, "array", outputs["type"])
+	require.Equal(t, []any{"stdout", "file:logs/app.log"}, outputs["default"])
+
+	_, err = GenerateSchema("not a struct")
+	require.Error(t, err)
+}
+
+type schemaNode struct {
+	Name     string
+	Next     *schemaNode
+	Children []schemaNode
+}
+
+// schemaNodeAlias names the package level schemaNode where a local type
+// shadows it.
+type schemaNodeAlias = schemaNode
+
+func TestGenerateSchemaSpecialTypes(t *testing.T) {
+	type Config struct {
+		Timeout  time.Duration `def:"5s" enum:"1s,5s"`
+		Deadline time.Time
+		Head     *schemaNode
+		Parent   *Config
+	}
+
+	schema, err := GenerateSchema(&Config{})
+	require.NoError(t, err)
+
+	require.Equal(t, &JSONSchema{Type: "string", Default: "5s", Enum: []any{"1s", "5s"}}, schema.Properties["timeout"])
+	require.Equal(t, &JSONSchema{Type: "string", Format: "date-time"}, schema.Properties["deadline"])
+	require.Equal(t, &JSONSchema{Ref: "#"}, schema.Properties["parent"])
+
+	require.Equal(t, &JSONSchema{Ref: "#/$defs/schemaNode"}, schema.Properties["head"])
+	node := schema.Defs["schemaNode"]
+	require.NotNil(t, node)
+	require.Equal(t, &JSONSchema{Ref: "#/$defs/schemaNode"}, node.Properties["next"])
+	require.Equal(t, &JSONSchema{Type: "array", Items: &JSONSchema{Ref: "#/$defs/schemaNode"}}, node.Properties["children"])
+}
+
+func TestGenerateSchemaDefNames(t *testing.T) {
+	type schemaNode struct {
+		Value int
+		Next  *schemaNode
+	}
+	type Config struct {
+		Local  *schemaNode
+		Shared *struct{ Head *schemaNode }
+		Global *schemaNodeAlias
+		Inline struct{ Port int }
+	}
+
+	schema, err := GenerateSchema(&Config{})
+	require.NoError(t, err)
+
+	// the package level and the local schemaNode share a name and a package
+	require.Equal(t, &JSONSchema{Ref: "#/$defs/schemaNode"}, schema.Properties["local"])
+	require.Equal(t, &JSONSchema{Ref: "#/$defs/schemaNode"}, schema.Properties["shared"].Properties["head"])
+	require.Equal(t, &JSONSchema{Ref: "#/$defs/github.com_adrenaissance_ascanius.schemaNode"}, schema.Properties["global"])
+	require.Len(t, schema.Defs, 2)
+	require.Contains(t, schema.Defs["schemaNode"].Properties, "value")
+	require.Contains(t, schema.Defs["github.com_adrenaissance_ascanius.schemaNode"].Properties, "children")
+
+	require.Equal(t, "object", schema.Properties["inline"].Type)
+	require.Contains(t, schema.Properties["inline"].Properties, "port")
+}
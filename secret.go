@@ -0,0 +1,332 @@
+package ascanius
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const SECRET_MASK = "******"
+
+// Key patterns whose values are always treated as secrets.
+// Patterns use path.Match syntax and are matched against both the leaf key
+// and the full dotted key path.
+var DEFAULT_SECRET_PATTERNS = []string{"*password*", "*token*"}
+
+// Secret wraps a config value that must never be printed.
+// It binds like the wrapped type but formats, logs and marshals as a mask.
+type Secret[T any] struct {
+	value T
+}
+
+func NewSecret[T any](value T) Secret[T] {
+	return Secret[T]{value: value}
+}
+
+// Value returns the wrapped value.
+func (s Secret[T]) Value() T {
+	return s.value
+}
+
+func (s Secret[T]) String() string {
+	return SECRET_MASK
+}
+
+func (s Secret[T]) GoString() string {
+	return SECRET_MASK
+}
+
+func (s Secret[T]) Format(f fmt.State, verb rune) {
+	fmt.Fprint(f, SECRET_MASK)
+}
+
+func (s Secret[T]) LogValue() slog.Value {
+	return slog.StringValue(SECRET_MASK)
+}
+
+func (s Secret[T]) MarshalText() ([]byte, error) {
+	return []byte(SECRET_MASK), nil
+}
+
+func (s Secret[T]) secretRaw() any {
+	return s.value
+}
+
+func (s *Secret[T]) secretValue() reflect.Value {
+	return reflect.ValueOf(&s.value).Elem()
+}
+
+type secretHolder interface {
+	secretValue() reflect.Value
+}
+
+type secretReader interface {
+	secretRaw() any
+}
+
+var secretHolderType = reflect.TypeOf((*secretHolder)(nil)).Elem()
+
+func isSecretField(field reflect.StructField) bool {
+	return field.Tag.Get("secret") == "true" || reflect.PointerTo(field.Type).Implements(secretHolderType)
+}
+
+// secretValue returns the settable value wrapped by a Secret field.
+func secretValue(fieldVal reflect.Value) (reflect.Value, bool) {
+	if !fieldVal.CanAddr() {
+		return reflect.Value{}, false
+	}
+	holder, ok := fieldVal.Addr().Interface().(secretHolder)
+	if !ok {
+		return reflect.Value{}, false
+	}
+	return holder.secretValue(), true
+}
+
+// SecretKeys adds key patterns whose values are masked in exports,
+// logs and error messages, e.g. "*api_key*" or "mongo.uri".
+func (b *Builder) SecretKeys(patterns ...string) *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, p := range patterns {
+		b.secretPatterns = append(b.secretPatterns, strings.ToLower(p))
+	}
+	return b
+}
+
+// RevealSecrets disables masking in Export and ExportStruct, e.g. when
+// generating deployment artefacts. Logs and errors stay masked.
+func (b *Builder) RevealSecrets() *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.revealSecrets = true
+	return b
+}
+
+// isSecretPath is isSecretKey for callers not holding b.mu.
+func (b *Builder) isSecretPath(key string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.isSecretKey(key, nil)
+}
+
+// redactedValue returns a copy of v, found at key, with the secrets nested in
+// it masked.
+func (b *Builder) redactedValue(v any, key string) any {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.redactValue(v, key, nil)
+}
+
+func (b *Builder) isSecretKey(key string, extra map[string]bool) bool {
+	if b.secretPaths[key] || extra[key] {
+		return true
+	}
+	leaf := key[strings.LastIndex(key, ".")+1:]
+	for _, p := range b.secretPatterns {
+		if ok, _ := path.Match(p, leaf); ok {
+			return true
+		}
+		if ok, _ := path.Match(p, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// redact returns a copy of data where every secret value is replaced by SECRET_MASK.
+func (b *Builder) redact(data map[string]any, parent string, extra map[string]bool) map[string]any {
+	out := make(map[string]any, len(data))
+	for k, v := range data {
+		key := joinKey(parent, k)
+		if b.isSecretKey(key, extra) && v != nil {
+			out[k] = SECRET_MASK
+			continue
+		}
+		out[k] = b.redactValue(v, key, extra)
+	}
+	return out
+}
+
+func (b *Builder) redactValue(v any, key string, extra map[string]bool) any {
+	switch val := v.(type) {
+	case map[string]any:
+		return b.redact(val, key, extra)
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = b.redactValue(item, key, extra)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// minRedactedSecretLength is the length below which secrets are not masked
+// in error messages, where they would match unrelated text.
+const minRedactedSecretLength = 4
+
+// secretStrings returns the secret strings found in data that can be
+// masked in error messages, longest first so that overlapping secrets are
+// fully masked. Short secrets and ones that read as booleans or numbers are
+// left out, since replacing them would corrupt the message.
+func (b *Builder) secretStrings(data map[string]any, parent string) []string {
+	var out []string
+	for k, v := range data {
+		b.appendSecretStrings(&out, v, joinKey(parent, k))
+	}
+	sort.Slice(out, func(i, j int) bool { return len(out[i]) > len(out[j]) })
+	return out
+}
+
+// appendSecretStrings appends the secret strings of v, stored at key. List
+// items are looked up under the key of their list, like redactValue does.
+func (b *Builder) appendSecretStrings(out *[]string, v any, key string) {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, item := range val {
+			b.appendSecretStrings(out, item, joinKey(key, k))
+		}
+	case []any:
+		for _, item := range val {
+			b.appendSecretStrings(out, item, key)
+		}
+	case string:
+		if len(val) < minRedactedSecretLength || !b.isSecretKey(key, nil) {
+			return
+		}
+		switch inferValue(val).(type) {
+		case bool, int64, float64:
+		default:
+			*out = append(*out, val)
+		}
+	}
+}
+
+type redactedError struct {
+	err     error
+	secrets []string
+}
+
+func (e *redactedError) Error() string {
+	msg := e.err.Error()
+	for _, s := range e.secrets {
+		msg = strings.ReplaceAll(msg, s, SECRET_MASK)
+	}
+	return msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+func (b *Builder) redactErrs(errs []error) []error {
+	if len(errs) == 0 || b.merged == nil {
+		return errs
+	}
+	secrets := b.secretStrings(b.merged, "")
+	if len(secrets) == 0 {
+		return errs
+	}
+	out := make([]error, len(errs))
+	for i, err := range errs {
+		out[i] = &redactedError{err: err, secrets: secrets}
+	}
+	return out
+}
+
+// RedactedConfig is a printable view of a configuration with every secret masked.
+// It implements fmt.Stringer and slog.LogValuer.
+type RedactedConfig struct {
+	data map[string]any
+	err  error
+}
+
+// Redacted returns a masked view of target, a bound config struct.
+// A nil target returns a view of the merged configuration.
+func (b *Builder) Redacted(target any) RedactedConfig {
+	b.mu.Lock()
+	defer b.unlock()
+
+	if target == nil {
+		return RedactedConfig{data: b.redact(b.current(), "", nil)}
+	}
+	secrets := make(map[string]bool)
+	data, err := structToMap(target, secrets, "")
+	if err != nil {
+		return RedactedConfig{err: err}
+	}
+	return RedactedConfig{data: b.redact(data, "", secrets)}
+}
+
+// Lookup returns the masked value stored at a dotted key path.
+func (r RedactedConfig) Lookup(key string) (any, bool) {
+	if r.err != nil {
+		return nil, false
+	}
+	return lookupPath(r.data, normalizeKeyPath(key))
+}
+
+func (r RedactedConfig) String() string {
+	if r.err != nil {
+		return r.err.Error()
+	}
+	out, err := json.Marshal(jsonFloats(r.data))
+	if err != nil {
+		return err.Error()
+	}
+	return string(out)
+}
+
+func (r RedactedConfig) LogValue() slog.Value {
+	if r.err != nil {
+		return slog.StringValue(r.err.Error())
+	}
+	return mapLogValue(r.data)
+}
+
+// String returns the masked configuration of the last load. Unlike
+// Redacted(nil), it never loads: an unloaded builder prints as {}.
+func (b *Builder) String() string {
+	return b.loadedView().String()
+}
+
+// LogValue is String for slog.
+func (b *Builder) LogValue() slog.Value {
+	return b.loadedView().LogValue()
+}
+
+func (b *Builder) loadedView() RedactedConfig {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return RedactedConfig{data: b.redact(b.merged, "", nil)}
+}
+
+func mapLogValue(data map[string]any) slog.Value {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]slog.Attr, 0, len(keys))
+	for _, k := range keys {
+		if sub, ok := data[k].(map[string]any); ok {
+			attrs = append(attrs, slog.Attr{Key: k, Value: mapLogValue(sub)})
+			continue
+		}
+		attrs = append(attrs, slog.Any(k, data[k]))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+func joinKey(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
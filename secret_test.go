@@ -0,0 +1,114 @@
+package ascanius
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretRedaction(t *testing.T) {
+	t.Setenv("APP__DB__USER", "admin")
+	t.Setenv("APP__DB__PASSWORD", "hunter2")
+	t.Setenv("APP__DB__DSN", "postgres://admin:s3cr3t@db")
+	t.Setenv("APP__API__TOKEN", "tok-123")
+	t.Setenv("APP__API__PORT", "8080")
+
+	type Config struct {
+		Db struct {
+			User     string
+			Password string
+			Dsn      string `secret:"true"`
+		}
+		Api struct {
+			Token Secret[string]
+			Port  Secret[int] `def:"80"`
+		}
+	}
+
+	var cfg Config
+	builder := New().Source("env", 1).Load(&cfg)
+	require.False(t, builder.HasErrs(), builder.Errs())
+
+	require.Equal(t, "hunter2", cfg.Db.Password)
+	require.Equal(t, "postgres://admin:s3cr3t@db", cfg.Db.Dsn)
+	require.Equal(t, "tok-123", cfg.Api.Token.Value())
+	require.Equal(t, 8080, cfg.Api.Port.Value())
+
+	for _, out := range []string{
+		fmt.Sprintf("%v %+v %#v %s", cfg.Api.Token, cfg.Api.Token, cfg.Api.Token, cfg.Api.Token),
+		builder.String(),
+		builder.Redacted(&cfg).String(),
+	} {
+		require.NotContains(t, out, "hunter2")
+		require.NotContains(t, out, "s3cr3t")
+		require.NotContains(t, out, "tok-123")
+	}
+	require.Contains(t, builder.String(), `"user":"admin"`)
+	require.NotContains(t, fmt.Sprintf("%v", cfg.Api), "tok-123")
+
+	var export bytes.Buffer
+	require.NoError(t, builder.Export(&export, "yaml"))
+	require.NotContains(t, export.String(), "hunter2")
+	require.NotContains(t, export.String(), "s3cr3t")
+
+	export.Reset()
+	require.NoError(t, builder.ExportStruct(&export, "json", &cfg))
+	require.NotContains(t, export.String(), "tok-123")
+	require.NotContains(t, export.String(), "8080")
+
+	var logs bytes.Buffer
+	slog.New(slog.NewJSONHandler(&logs, nil)).Info("config", "cfg", builder.Redacted(&cfg), "builder", builder)
+	require.NotContains(t, logs.String(), "hunter2")
+	require.NotContains(t, logs.String(), "tok-123")
+	require.Contains(t, logs.String(), "admin")
+
+	leak := errors.New("cannot parse hunter2")
+	builder.errs = append(builder.errs, leak)
+	errs := builder.Errs()
+	require.Equal(t, "cannot parse ******", errs[0].Error())
+	require.ErrorIs(t, errs[0], leak)
+}
+
+func TestRedactedErrorsKeepShortSecrets(t *testing.T) {
+	var cfg struct{}
+	builder := New().AddSource(&stubSource{name: "stub", priority: 1, data: map[string]any{
+		"pin_password": "1",
+		"flag_token":   "true",
+		"api_token":    "abcd-1234",
+	}}).Load(&cfg)
+	require.False(t, builder.HasErrs(), builder.Errs())
+
+	builder.errs = append(builder.errs, errors.New("field 1 of a list: true expected, got abcd-1234"))
+	require.Equal(t, "field 1 of a list: true expected, got ******", builder.Errs()[0].Error())
+}
+
+func TestSecretKeyPatterns(t *testing.T) {
+	builder := New().SecretKeys("*dsn*", "api.key")
+	require.True(t, builder.isSecretKey("db.password", nil))
+	require.True(t, builder.isSecretKey("db.mongo_dsn", nil))
+	require.True(t, builder.isSecretKey("api.key", nil))
+	require.False(t, builder.isSecretKey("other.key", nil))
+}
+
+func TestRedactedErrorsMaskListSecrets(t *testing.T) {
+	var cfg struct{}
+	builder := New().AddSource(&stubSource{name: "stub", priority: 1, data: map[string]any{
+		"api_tokens": []any{"tok-first", "tok-second"},
+		"servers":    []any{map[string]any{"password": "hunter22"}},
+	}}).Load(&cfg)
+	require.False(t, builder.HasErrs(), builder.Errs())
+
+	builder.errs = append(builder.errs, errors.New("rejected tok-second and hunter22"))
+	require.Equal(t, "rejected ****** and ******", builder.Errs()[0].Error())
+}
+
+func TestBuilderStringDoesNotLoad(t *testing.T) {
+	builder := New().Source("missing.json", 1)
+	require.Equal(t, "{}", builder.String())
+	require.Equal(t, "{}", fmt.Sprint(builder))
+	require.False(t, builder.HasErrs(), builder.Errs())
+}
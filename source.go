@@ -1,5 +1,12 @@
 package ascanius
 
+import (
+	"context"
+	"fmt"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
 // A source is a config values container
 // This can be the environment, a file, a map, a struct ecc...
 // anything that contains values that will be loaded inside of a config struct
@@ -19,3 +26,70 @@ type Source interface {
 	// set the name of the source
 	SetName(string)
 }
+
+// ContextSource is a source whose loading can be cancelled, e.g. a remote
+// source waiting on the network.
+type ContextSource interface {
+	Source
+
+	// load the source values, giving up once ctx is done
+	LoadContext(ctx context.Context) (map[string]any, error)
+}
+
+// AdaptContext returns src as a ContextSource. Sources that only implement
+// Load are run in a goroutine which is abandoned once ctx is done.
+func AdaptContext(src Source) ContextSource {
+	if cs, ok := src.(ContextSource); ok {
+		return cs
+	}
+	return contextAdapter{src}
+}
+
+type contextAdapter struct {
+	Source
+}
+
+func (a contextAdapter) LoadContext(ctx context.Context) (map[string]any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		data map[string]any
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		data, err := a.Load()
+		done <- result{data, err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.data, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// decodeFormat parses a JSON, YAML or TOML document into a map. YAML is
+// decoded like YamlSource decodes it, with profile selecting documents.
+func decodeFormat(format string, data []byte, profile string) (map[string]any, error) {
+	result := make(map[string]any)
+
+	var err error
+	switch format {
+	case JSON_SOURCE_NAME:
+		err = unmarshalJson(data, &result)
+	case YAML_SOURCE_NAME:
+		result, err = decodeYaml(data, profile)
+	case TOML_SOURCE_NAME:
+		err = toml.Unmarshal(data, &result)
+	default:
+		err = fmt.Errorf("unsupported format %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,90 @@
+package ascanius
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubSource returns data after delay, or blocks until release is closed.
+// started, when set, is marked done as soon as Load is called.
+type stubSource struct {
+	name     string
+	priority int
+	data     map[string]any
+	delay    time.Duration
+	release  chan struct{}
+	started  *sync.WaitGroup
+}
+
+func (s *stubSource) Name() string        { return s.name }
+func (s *stubSource) SetName(name string) { s.name = name }
+func (s *stubSource) Priority() int       { return s.priority }
+func (s *stubSource) SetPriority(p int)   { s.priority = p }
+
+func (s *stubSource) Load() (map[string]any, error) {
+	if s.started != nil {
+		s.started.Done()
+	}
+	if s.release != nil {
+		<-s.release
+	}
+	time.Sleep(s.delay)
+	return s.data, nil
+}
+
+func TestAdaptContext(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	src := AdaptContext(&stubSource{name: "slow", release: release})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := src.LoadContext(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	kv := NewKVSource(NewMemoryKVStore(), "app/", "", 1)
+	assert.Same(t, kv, AdaptContext(kv))
+}
+
+func TestLoadContextSourceTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	var cfg struct {
+		Host string
+		Port int
+	}
+	b := New().
+		AddSource(&stubSource{name: "defaults", priority: 1, data: map[string]any{"host": "localhost", "port": 80}}).
+		AddSource(&stubSource{name: "network-mount", priority: 2, release: release}).
+		SourceTimeout("network-mount", 20*time.Millisecond).
+		LoadContext(context.Background(), &cfg)
+
+	require.Len(t, b.Errs(), 1)
+	assert.ErrorIs(t, b.Errs()[0], context.DeadlineExceeded)
+	assert.Contains(t, b.Errs()[0].Error(), "source network-mount: timed out after 20ms")
+	assert.Equal(t, "localhost", cfg.Host)
+	assert.Equal(t, 80, cfg.Port)
+}
+
+func TestLoadContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var cfg struct{ Host string }
+	b := New().
+		AddSource(&stubSource{name: "defaults", priority: 1, data: map[string]any{"host": "localhost"}}).
+		LoadTimeout(time.Second).
+		LoadContext(ctx, &cfg)
+
+	require.Len(t, b.Errs(), 1)
+	assert.True(t, errors.Is(b.Errs()[0], context.Canceled))
+	assert.Empty(t, cfg.Host)
+}
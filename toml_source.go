@@ -1,9 +1,17 @@
 package ascanius
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
 )
 
 const TOML_SOURCE_NAME = "toml"
@@ -12,6 +20,7 @@ type TomlSource struct {
 	name     string
 	path     string
 	priority int
+	state    *fileState
 }
 
 func NewTomlSource(path string, name string, priority int) *TomlSource {
@@ -22,6 +31,7 @@ func NewTomlSource(path string, name string, priority int) *TomlSource {
 		name:     name,
 		path:     path,
 		priority: priority,
+		state:    &fileState{},
 	}
 }
 
@@ -35,8 +45,9 @@ func (t *TomlSource) Load() (map[string]any, error) {
 
 	err = toml.Unmarshal(bytes, &result)
 	if err != nil {
-		return nil, err
+		return nil, newParseError(t.path, bytes, err)
 	}
+	t.state.loaded(bytes)
 
 	return result, nil
 }
@@ -46,3 +57,377 @@ func (t *TomlSource) SetName(name string) { t.name = name }
 func (t *TomlSource) Priority() int       { return t.priority }
 func (t *TomlSource) SetPriority(p int)   { t.priority = p }
 func (t *TomlSource) Type() string        { return TOML_SOURCE_NAME }
+
+// Set records a new value for key, written by Save.
+func (t *TomlSource) Set(key string, value any) error {
+	return t.state.set(t.path, key, value)
+}
+
+// Save writes the values recorded by Set. Values are replaced in place and
+// new keys are added to the table they belong to, so comments, order and
+// formatting are kept.
+func (t *TomlSource) Save() error {
+	return t.state.save(t.path, editToml)
+}
+
+func (t *TomlSource) hasPending() bool {
+	return t.state.hasPending()
+}
+
+// tomlEntry is a key/value pair or table header of a TOML document.
+type tomlEntry struct {
+	// path is the normalized key path of the value or table
+	path []string
+	// table is the path of the table a key/value pair belongs to
+	table  []string
+	header bool
+	// array is set for array tables and the entries inside them
+	array bool
+	// valueStart and valueEnd delimit the value of a key/value pair
+	valueStart, valueEnd int
+	// lineStart is the offset of the line the entry starts on and lineEnd
+	// the offset after the line it ends on
+	lineStart, lineEnd int
+}
+
+func scanTomlEntries(content []byte) ([]tomlEntry, error) {
+	var (
+		entries []tomlEntry
+		table   []string
+		array   bool
+		arrays  [][]string
+		p       unstable.Parser
+	)
+	p.Reset(content)
+	for p.NextExpression() {
+		expr := p.Expression()
+		var keys []string
+		keyStart, keyEnd := -1, 0
+		it := expr.Key()
+		for it.Next() {
+			k := it.Node()
+			keys = append(keys, toSnakeCase(string(k.Data)))
+			if keyStart < 0 {
+				keyStart = int(k.Raw.Offset)
+			}
+			keyEnd = int(k.Raw.Offset + k.Raw.Length)
+		}
+		lineStart := bytes.LastIndexByte(content[:max(keyStart, 0)], '\n') + 1
+
+		switch expr.Kind {
+		case unstable.Table, unstable.ArrayTable:
+			table = keys
+			array = expr.Kind == unstable.ArrayTable
+			for _, a := range arrays {
+				array = array || hasKeyPrefix(table, a)
+			}
+			if expr.Kind == unstable.ArrayTable {
+				arrays = append(arrays, keys)
+			}
+			end := bytes.IndexByte(content[keyEnd:], ']') + keyEnd + 1
+			entries = append(entries, tomlEntry{path: keys, table: keys, header: true, array: array, lineStart: lineStart, lineEnd: tomlLineEnd(content, end)})
+
+		case unstable.KeyValue:
+			start := keyEnd
+			for start < len(content) && (content[start] == ' ' || content[start] == '\t' || content[start] == '=') {
+				start++
+			}
+			end := tomlValueEnd(content, start)
+			entries = append(entries, tomlEntry{
+				path:       append(append([]string{}, table...), keys...),
+				table:      table,
+				array:      array,
+				valueStart: start,
+				valueEnd:   end,
+				lineStart:  lineStart,
+				lineEnd:    tomlLineEnd(content, end),
+			})
+		}
+	}
+	return entries, p.Error()
+}
+
+func hasKeyPrefix(path, prefix []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if path[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func tomlLineEnd(content []byte, offset int) int {
+	if i := bytes.IndexByte(content[offset:], '\n'); i >= 0 {
+		return offset + i + 1
+	}
+	return len(content)
+}
+
+// tomlValueEnd returns the offset just past the value starting at i.
+func tomlValueEnd(content []byte, i int) int {
+	if i >= len(content) {
+		return i
+	}
+	rest := content[i:]
+	switch {
+	case bytes.HasPrefix(rest, []byte(`"""`)), bytes.HasPrefix(rest, []byte("'''")):
+		delim := rest[:3]
+		for j := 3; j < len(rest); j++ {
+			if rest[j] == '\\' && delim[0] == '"' {
+				j++
+				continue
+			}
+			if bytes.HasPrefix(rest[j:], delim) {
+				// up to two quotes may precede the closing delimiter
+				for j+3 < len(rest) && rest[j+3] == delim[0] {
+					j++
+				}
+				return i + j + 3
+			}
+		}
+		return len(content)
+
+	case rest[0] == '"' || rest[0] == '\'':
+		for j := 1; j < len(rest); j++ {
+			if rest[j] == '\\' && rest[0] == '"' {
+				j++
+				continue
+			}
+			if rest[j] == rest[0] {
+				return i + j + 1
+			}
+		}
+		return len(content)
+
+	case rest[0] == '[' || rest[0] == '{':
+		depth := 0
+		for j := 0; j < len(rest); j++ {
+			switch rest[j] {
+			case '"', '\'':
+				j = tomlValueEnd(content, i+j) - i - 1
+			case '#':
+				for j < len(rest) && rest[j] != '\n' {
+					j++
+				}
+			case '[', '{':
+				depth++
+			case ']', '}':
+				depth--
+				if depth == 0 {
+					return i + j + 1
+				}
+			}
+		}
+		return len(content)
+
+	default:
+		j := 0
+		for j < len(rest) && !strings.ContainsRune(" \t\r\n,#]}", rune(rest[j])) {
+			j++
+		}
+		// the time of a date-time may follow a space
+		if j+3 < len(rest) && rest[j] == ' ' && isDigit(rest[j+1]) && isDigit(rest[j+2]) && rest[j+3] == ':' {
+			return tomlValueEnd(content, i+j+1)
+		}
+		return i + j
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+var bareTomlKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// tomlString quotes s as a TOML basic string.
+func tomlString(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\r':
+			sb.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&sb, `\u%04X`, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+func tomlKey(parts []string) string {
+	quoted := make([]string, len(parts))
+	for i, part := range parts {
+		if bareTomlKey.MatchString(part) {
+			quoted[i] = part
+		} else {
+			quoted[i] = tomlString(part)
+		}
+	}
+	return strings.Join(quoted, ".")
+}
+
+func tomlValue(value any) (string, error) {
+	if value == nil {
+		return "", errors.New("TOML has no null value")
+	}
+	if s, ok := value.(string); ok {
+		return tomlString(s), nil
+	}
+	var buf bytes.Buffer
+	enc := toml.NewEncoder(&buf).SetTablesInline(true)
+	if err := enc.Encode(map[string]any{"v": value}); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(buf.String(), "v = "), "\n"), nil
+}
+
+// editToml sets e in a TOML document by splicing text: an existing value is
+// replaced, a new key is added after the last entry of the closest table
+// that contains it, or in a new table at the end of the document. Removing
+// a key deletes its line, and the header of its table once it is empty.
+func editToml(content []byte, e fileEdit) ([]byte, error) {
+	edited, err := spliceToml(content, e)
+	if err != nil {
+		return nil, err
+	}
+	var check map[string]any
+	if err := toml.Unmarshal(edited, &check); err != nil {
+		return nil, fmt.Errorf("the edit would make the file invalid: %w", err)
+	}
+	// make sure the edit produced the document we expect
+	got, ok := lookupPath(normalizeKeysToSnakeCase(check), e.key)
+	switch {
+	case e.remove && ok:
+		return nil, errors.New("the key is still set after the edit")
+	case !e.remove && (!ok || !sameJsonValue(got, e.value)):
+		return nil, fmt.Errorf("the edit would not set the key to %v", e.value)
+	}
+	return edited, nil
+}
+
+// sameJsonValue reports whether a and b have the same JSON encoding, so
+// that e.g. int and int64 values compare equal.
+func sameJsonValue(a, b any) bool {
+	rawA, errA := json.Marshal(a)
+	rawB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(rawA, rawB)
+}
+
+func spliceToml(content []byte, e fileEdit) ([]byte, error) {
+	entries, err := scanTomlEntries(content)
+	if err != nil {
+		return nil, err
+	}
+	if e.remove {
+		return removeToml(content, entries, strings.Split(e.key, "."))
+	}
+	value, err := tomlValue(e.value)
+	if err != nil {
+		return nil, err
+	}
+	path := strings.Split(e.key, ".")
+
+	var (
+		best     = 0
+		insertAt = 0
+		table    []string
+	)
+	for _, entry := range entries {
+		if entry.array {
+			continue
+		}
+		if !entry.header {
+			if slices.Equal(entry.path, path) {
+				return splice(content, entry.valueStart, entry.valueEnd, value), nil
+			}
+			if hasKeyPrefix(path, entry.path) {
+				return nil, fmt.Errorf("%s is not a table", strings.Join(entry.path, "."))
+			}
+			// dotted keys define the tables on their path
+			if prefix := len(entry.path) - 1; hasKeyPrefix(path, entry.path[:prefix]) && prefix >= best {
+				best, insertAt, table = prefix, entry.lineEnd, entry.table
+			}
+			continue
+		}
+		if len(entry.path) < len(path) && hasKeyPrefix(path, entry.path) && len(entry.path) >= best {
+			best, insertAt, table = len(entry.path), entry.lineEnd, entry.path
+		}
+	}
+
+	var line string
+	if best == 0 && len(path) > 1 {
+		// start a new table at the end of the document
+		insertAt = len(content)
+		line = fmt.Sprintf("[%s]\n%s = %s\n", tomlKey(path[:len(path)-1]), tomlKey(path[len(path)-1:]), value)
+		if !bytes.HasSuffix(content, []byte("\n\n")) {
+			line = "\n" + line
+		}
+	} else {
+		line = fmt.Sprintf("%s = %s\n", tomlKey(path[len(table):]), value)
+	}
+	if insertAt > 0 && content[insertAt-1] != '\n' {
+		line = "\n" + line
+	}
+	return splice(content, insertAt, insertAt, line), nil
+}
+
+func removeToml(content []byte, entries []tomlEntry, path []string) ([]byte, error) {
+	for i, entry := range entries {
+		if entry.header || entry.array {
+			continue
+		}
+		if !slices.Equal(entry.path, path) {
+			if hasKeyPrefix(path, entry.path) {
+				return nil, fmt.Errorf("cannot remove a key inside the value of %s", strings.Join(entry.path, "."))
+			}
+			continue
+		}
+
+		edited := splice(content, entry.lineStart, entry.lineEnd, "")
+		if len(entry.table) == 0 {
+			return edited, nil
+		}
+		// drop the header of a table left empty
+		header := -1
+		for j, other := range entries {
+			switch {
+			case j == i:
+			case other.header && slices.Equal(other.path, entry.table):
+				header = j
+			case hasKeyPrefix(other.path, entry.table):
+				return edited, nil
+			}
+		}
+		if header >= 0 {
+			edited = splice(edited, entries[header].lineStart, entries[header].lineEnd, "")
+		}
+		return edited, nil
+	}
+	return content, nil
+}
+
+func splice(content []byte, start, end int, text string) []byte {
+	out := make([]byte, 0, len(content)-(end-start)+len(text))
+	out = append(out, content[:start]...)
+	out = append(out, text...)
+	return append(out, content[end:]...)
+}
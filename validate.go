@@ -0,0 +1,187 @@
+package ascanius
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+const schemaResourceURL = "ascanius://schema.json"
+
+var schemaPrinter = message.NewPrinter(language.English)
+
+// SchemaValidator checks raw configuration maps against a compiled JSON Schema.
+// Meta-schemas are bundled and only local file references are resolved,
+// so validation never touches the network.
+type SchemaValidator struct {
+	schema *jsonschema.Schema
+}
+
+// ValidationError is a single schema violation.
+type ValidationError struct {
+	Source  string // source name, empty for the merged configuration
+	Pointer string // JSON pointer of the offending value
+	Keyword string // schema keyword that failed, e.g. "type" or "required"
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	source := e.Source
+	if source == "" {
+		source = "merged configuration"
+	}
+	pointer := e.Pointer
+	if pointer == "" {
+		pointer = "/"
+	}
+	return fmt.Sprintf("%s: %s: %s: %s", source, pointer, e.Keyword, e.Message)
+}
+
+// CompileSchema compiles a JSON Schema document.
+func CompileSchema(doc []byte) (*SchemaValidator, error) {
+	return compileSchema(doc, schemaResourceURL)
+}
+
+// CompileSchemaFile compiles the JSON Schema stored at path.
+// Relative $ref values are resolved against the schema's directory.
+func CompileSchemaFile(path string) (*SchemaValidator, error) {
+	doc, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	return compileSchema(doc, "file://"+filepath.ToSlash(abs))
+}
+
+func compileSchema(doc []byte, url string) (*SchemaValidator, error) {
+	parsed, err := jsonschema.UnmarshalJSON(bytes.NewReader(doc))
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	c := jsonschema.NewCompiler()
+	c.UseLoader(jsonschema.SchemeURLLoader{"file": jsonschema.FileLoader{}})
+	if err := c.AddResource(url, parsed); err != nil {
+		return nil, err
+	}
+	schema, err := c.Compile(url)
+	if err != nil {
+		return nil, err
+	}
+	return &SchemaValidator{schema: schema}, nil
+}
+
+// Validate checks data against the schema and returns one *ValidationError
+// per violation, sorted by pointer.
+func (v *SchemaValidator) Validate(source string, data map[string]any) []error {
+	instance, err := toJsonValue(data)
+	if err != nil {
+		return []error{&ValidationError{Source: source, Message: err.Error()}}
+	}
+
+	err = v.schema.Validate(instance)
+	if err == nil {
+		return nil
+	}
+	verr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return []error{&ValidationError{Source: source, Message: err.Error()}}
+	}
+
+	var errs []*ValidationError
+	collectViolations(&errs, source, verr)
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Pointer < errs[j].Pointer })
+
+	out := make([]error, len(errs))
+	for i, e := range errs {
+		out[i] = e
+	}
+	return out
+}
+
+func collectViolations(dst *[]*ValidationError, source string, err *jsonschema.ValidationError) {
+	if len(err.Causes) > 0 {
+		for _, cause := range err.Causes {
+			collectViolations(dst, source, cause)
+		}
+		return
+	}
+
+	pointer := ""
+	for _, tok := range err.InstanceLocation {
+		tok = strings.ReplaceAll(tok, "~", "~0")
+		pointer += "/" + strings.ReplaceAll(tok, "/", "~1")
+	}
+
+	*dst = append(*dst, &ValidationError{
+		Source:  source,
+		Pointer: pointer,
+		Keyword: strings.Join(err.ErrorKind.KeywordPath(), "/"),
+		Message: err.ErrorKind.LocalizedString(schemaPrinter),
+	})
+}
+
+// toJsonValue converts decoded config data (which may contain format
+// specific types such as TOML dates) to plain JSON values.
+func toJsonValue(data map[string]any) (any, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+}
+
+// ValidateSources validates the named sources against the schema right after
+// they are loaded and their keys normalized. Without names every source is
+// validated. Sources that fail validation are not merged.
+func (b *Builder) ValidateSources(v *SchemaValidator, names ...string) *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(names) == 0 {
+		b.sourceSchemas[""] = append(b.sourceSchemas[""], v)
+		return b
+	}
+	for _, name := range names {
+		b.sourceSchemas[name] = append(b.sourceSchemas[name], v)
+	}
+	return b
+}
+
+// ValidateMerged validates the merged configuration against the schema
+// before it is applied to the target.
+func (b *Builder) ValidateMerged(v *SchemaValidator) *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.mergedSchemas = append(b.mergedSchemas, v)
+	return b
+}
+
+func (b *Builder) validateSource(name string, data map[string]any) []error {
+	var errs []error
+	for _, v := range b.sourceSchemas[""] {
+		errs = append(errs, v.Validate(name, data)...)
+	}
+	for _, v := range b.sourceSchemas[name] {
+		errs = append(errs, v.Validate(name, data)...)
+	}
+	return errs
+}
+
+func (b *Builder) validateMerged(data map[string]any) []error {
+	var errs []error
+	for _, v := range b.mergedSchemas {
+		errs = append(errs, v.Validate("", data)...)
+	}
+	return errs
+}
@@ -0,0 +1,92 @@
+package ascanius
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const mongoSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"required": ["mongo"],
+	"properties": {
+		"mongo": {
+			"type": "object",
+			"required": ["host"],
+			"properties": {
+				"host": {"type": "string"},
+				"port": {"type": "integer", "maximum": 65535}
+			}
+		}
+	}
+}`
+
+func TestValidateSources(t *testing.T) {
+	schema, err := CompileSchema([]byte(mongoSchema))
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.json")
+	require.NoError(t, os.WriteFile(bad, []byte(`{"mongo": {"port": "27017"}}`), 0o644))
+
+	var cfg struct {
+		Mongo MongoConfig
+	}
+	builder := New().
+		Source("./files/mongo.yaml", 1).
+		Source(bad, 2).
+		ValidateSources(schema).
+		Load(&cfg)
+
+	errs := builder.Errs()
+	require.Len(t, errs, 2)
+
+	var verr *ValidationError
+	require.True(t, errors.As(errs[0], &verr))
+	require.Equal(t, bad, verr.Source)
+	require.Equal(t, "/mongo", verr.Pointer)
+	require.Equal(t, "required", verr.Keyword)
+
+	require.True(t, errors.As(errs[1], &verr))
+	require.Equal(t, "/mongo/port", verr.Pointer)
+	require.Equal(t, "type", verr.Keyword)
+	require.Contains(t, verr.Error(), bad+": /mongo/port: type:")
+
+	// the invalid source is not merged
+	require.Equal(t, uint16(27018), cfg.Mongo.Port)
+}
+
+func TestValidateMerged(t *testing.T) {
+	schema, err := CompileSchema([]byte(mongoSchema))
+	require.NoError(t, err)
+
+	var cfg struct {
+		Port int
+	}
+	builder := New().
+		Source("./files/flat.json", 1).
+		ValidateMerged(schema).
+		Load(&cfg)
+
+	errs := builder.Errs()
+	require.Len(t, errs, 1)
+	require.EqualError(t, errs[0], "merged configuration: /: required: missing property 'mongo'")
+}
+
+func TestCompileSchemaOffline(t *testing.T) {
+	_, err := CompileSchema([]byte(`{"$ref": "https://example.com/schema.json"}`))
+	require.Error(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "port.json"), []byte(`{"type": "integer"}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "root.json"), []byte(`{"properties": {"port": {"$ref": "port.json"}}}`), 0o644))
+
+	schema, err := CompileSchemaFile(filepath.Join(dir, "root.json"))
+	require.NoError(t, err)
+	require.Empty(t, schema.Validate("", map[string]any{"port": 1}))
+	require.Len(t, schema.Validate("", map[string]any{"port": "1"}), 1)
+}
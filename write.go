@@ -0,0 +1,295 @@
+package ascanius
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/adrenaissance/ascanius/internal/atomicfile"
+)
+
+// WritableSource is a file source whose values can be changed and written
+// back with Save.
+type WritableSource interface {
+	Source
+	// Set records a new value for the dotted key path key. Map values are
+	// set key by key. Nothing is written until Save.
+	Set(key string, value any) error
+	// Save writes the recorded changes to the file atomically. It fails
+	// with a *ModifiedError when the file changed since the source last
+	// read it.
+	Save() error
+}
+
+// ModifiedError is returned by Save when the file was changed by someone
+// else since the source last read it. Load the source again and retry.
+type ModifiedError struct {
+	Path string
+}
+
+func (e *ModifiedError) Error() string {
+	return fmt.Sprintf("%s was modified since it was loaded", e.Path)
+}
+
+// fileEdit is a change recorded by Set, with a normalized key path and a
+// value made of maps, slices and scalars. remove deletes the key instead,
+// along with the sections it leaves empty.
+type fileEdit struct {
+	key    string
+	value  any
+	remove bool
+}
+
+// fileState tracks the content a file source last read, so that Save can
+// detect changes made by others, and the edits waiting for Save.
+type fileState struct {
+	mu      sync.Mutex
+	read    bool
+	exists  bool
+	hash    [sha256.Size]byte
+	pending []fileEdit
+}
+
+// loaded records content as the version of the file the source knows.
+func (f *fileState) loaded(content []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.read, f.exists, f.hash = true, true, sha256.Sum256(content)
+}
+
+func (f *fileState) set(path, key string, value any) error {
+	value, err := writableValue(value)
+	if err != nil {
+		return fmt.Errorf("cannot set %s: %w", key, err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.read {
+		// edits are checked against the file as it is now
+		content, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			f.read = true
+		case err != nil:
+			return err
+		default:
+			f.read, f.exists, f.hash = true, true, sha256.Sum256(content)
+		}
+	}
+	f.pending = appendEdits(f.pending, normalizeKeyPath(key), value)
+	return nil
+}
+
+// appendEdits records value at key, splitting non-empty maps into one edit
+// per leaf so that keys missing from value are kept.
+func appendEdits(edits []fileEdit, key string, value any) []fileEdit {
+	m, ok := value.(map[string]any)
+	if !ok || len(m) == 0 {
+		return append(edits, fileEdit{key: key, value: value})
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		edits = appendEdits(edits, joinKey(key, normalizeKeyPath(k)), m[k])
+	}
+	return edits
+}
+
+func (f *fileState) hasPending() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.pending) > 0
+}
+
+// pendingSource is implemented by the writable sources of this package,
+// so that Save only drops the cached data of sources it wrote.
+type pendingSource interface {
+	hasPending() bool
+}
+
+// save applies the pending edits to the file at path with edit and writes
+// the result atomically, unless the file changed since it was read.
+func (f *fileState) save(path string, edit func(content []byte, e fileEdit) ([]byte, error)) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.pending) == 0 {
+		return nil
+	}
+
+	perm := fs.FileMode(0o644)
+	content, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		if f.exists {
+			return &ModifiedError{Path: path}
+		}
+	case err != nil:
+		return err
+	default:
+		if !f.exists || sha256.Sum256(content) != f.hash {
+			return &ModifiedError{Path: path}
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		perm = info.Mode().Perm()
+	}
+
+	for _, e := range f.pending {
+		if content, err = edit(content, e); err != nil {
+			return fmt.Errorf("%s: cannot set %s: %w", path, e.key, err)
+		}
+	}
+	if err := atomicfile.WriteFile(path, content, perm); err != nil {
+		return err
+	}
+	f.exists, f.hash, f.pending = true, sha256.Sum256(content), nil
+	return nil
+}
+
+// writableValue converts value to the maps, slices and scalars the file
+// formats can encode. Durations and encoding.TextMarshaler values are
+// written as text, other types through their JSON encoding.
+func writableValue(value any) (any, error) {
+	switch v := value.(type) {
+	case nil, bool, string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return v, nil
+	case json.Number:
+		return numberValue(v), nil
+	case time.Duration:
+		return v.String(), nil
+	case encoding.TextMarshaler:
+		text, err := v.MarshalText()
+		return string(text), err
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			converted, err := writableValue(item)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = converted
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			converted, err := writableValue(item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = converted
+		}
+		return out, nil
+	}
+
+	val := reflect.ValueOf(value)
+	if val.Kind() == reflect.String {
+		return val.String(), nil
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var generic any
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return writableValue(numbersToValues(generic))
+}
+
+func numberValue(n json.Number) any {
+	if i, err := n.Int64(); err == nil {
+		return i
+	}
+	f, _ := n.Float64()
+	return f
+}
+
+// numbersToValues replaces json.Number values with int64 or float64.
+func numbersToValues(v any) any {
+	switch val := v.(type) {
+	case json.Number:
+		return numberValue(val)
+	case map[string]any:
+		for k, item := range val {
+			val[k] = numbersToValues(item)
+		}
+	case []any:
+		for i, item := range val {
+			val[i] = numbersToValues(item)
+		}
+	}
+	return v
+}
+
+// WriteTo sets the source, by name, that Set changes.
+func (b *Builder) WriteTo(name string) *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.writeTarget = name
+	return b
+}
+
+// Set records a new value for the dotted key path key in the source chosen
+// with WriteTo. The change is written to the file, and seen by loads, once
+// Save is called.
+func (b *Builder) Set(key string, value any) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.writeTarget == "" {
+		return errors.New("no source to write to, call WriteTo first")
+	}
+	for _, src := range b.sources {
+		if src.Name() != b.writeTarget {
+			continue
+		}
+		ws, ok := src.(WritableSource)
+		if !ok {
+			return fmt.Errorf("source %s is not writable", src.Name())
+		}
+		return ws.Set(key, value)
+	}
+	return fmt.Errorf("source %s not found", b.writeTarget)
+}
+
+// Save writes the changes recorded by Set and drops the cached data of the
+// sources written, so the next load reads them back.
+func (b *Builder) Save() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var errs []error
+	for _, src := range b.sources {
+		ws, ok := src.(WritableSource)
+		if !ok {
+			continue
+		}
+		// sources of other packages may always write
+		if ps, ok := ws.(pendingSource); ok && !ps.hasPending() {
+			continue
+		}
+		if err := ws.Save(); err != nil {
+			errs = append(errs, &SourceError{Source: src.Name(), Err: err})
+			continue
+		}
+		delete(b.mapSource, src.Name())
+		b.merged = nil
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,223 @@
+package ascanius
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTemp(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o640))
+	return path
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestSaveJson(t *testing.T) {
+	path := writeTemp(t, "config.json", `{
+    "name": "api",
+    "mongo": {"replicaSet": "rs0", "port": 27017},
+    "hosts": ["a", "b"]
+}
+`)
+	src := NewJsonSource(path, "", 1)
+	require.NoError(t, src.Set("mongo.replica_set", "rs1"))
+	require.NoError(t, src.Set("mongo.tls.enabled", true))
+	require.NoError(t, src.Set("timeout", 30*time.Second))
+	require.NoError(t, src.Save())
+
+	assert.Equal(t, `{
+    "name": "api",
+    "mongo": {
+        "replicaSet": "rs1",
+        "port": 27017,
+        "tls": {
+            "enabled": true
+        }
+    },
+    "hosts": [
+        "a",
+        "b"
+    ],
+    "timeout": "30s"
+}
+`, readFile(t, path))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+}
+
+func TestSaveYaml(t *testing.T) {
+	path := writeTemp(t, "config.yaml", `# service settings
+name: api # the service name
+
+mongo:
+  # connection
+  host: "localhost"
+  port: 27017
+`)
+	src := NewYamlSource(path, "", 1)
+	require.NoError(t, src.Set("mongo.host", "db.internal"))
+	require.NoError(t, src.Set("mongo.port", 27018))
+	require.NoError(t, src.Save())
+
+	// scalars are edited in place
+	assert.Equal(t, `# service settings
+name: api # the service name
+
+mongo:
+  # connection
+  host: db.internal
+  port: 27018
+`, readFile(t, path))
+
+	require.NoError(t, src.Set("mongo.replica_set", "rs0"))
+	require.NoError(t, src.Set("name", map[string]any{"short": "api"}))
+	require.NoError(t, src.Save())
+	assert.Equal(t, `# service settings
+name: # the service name
+  short: api
+mongo:
+  # connection
+  host: db.internal
+  port: 27018
+  replica_set: rs0
+`, readFile(t, path))
+}
+
+func TestSaveToml(t *testing.T) {
+	path := writeTemp(t, "config.toml", `# service settings
+name = "api" # the service name
+started = 1979-05-27 07:32:00Z
+
+[mongo]
+# connection
+host = "localhost"
+ports = [
+  27017, # primary
+  27018,
+]
+
+[[replicas]]
+host = "replica"
+`)
+	src := NewTomlSource(path, "", 1)
+	require.NoError(t, src.Set("name", "worker"))
+	require.NoError(t, src.Set("started", "now"))
+	require.NoError(t, src.Set("mongo.ports", []any{1, 2}))
+	require.NoError(t, src.Set("mongo.replica_set", "rs0"))
+	require.NoError(t, src.Set("mongo.tls.enabled", true))
+	require.NoError(t, src.Set("log.level", "debug"))
+	require.NoError(t, src.Set("debug", true))
+	require.NoError(t, src.Save())
+
+	assert.Equal(t, `# service settings
+name = "worker" # the service name
+started = "now"
+debug = true
+
+[mongo]
+# connection
+host = "localhost"
+ports = [1, 2]
+replica_set = "rs0"
+tls.enabled = true
+
+[[replicas]]
+host = "replica"
+
+[log]
+level = "debug"
+`, readFile(t, path))
+
+	require.NoError(t, src.Set("mongo.host.name", "x"))
+	assert.ErrorContains(t, src.Save(), "mongo.host is not a table")
+}
+
+func TestSaveDotenv(t *testing.T) {
+	path := writeTemp(t, ".env", `# database
+APP__MONGO__HOST=localhost
+export APP__MONGO__PORT=27017
+OTHER=1`)
+	src := NewEnvSource(path, 1)
+	require.NoError(t, src.Set("mongo.port", 27018))
+	require.NoError(t, src.Set("mongo.replica_set", "rs0"))
+	require.NoError(t, src.Save())
+
+	assert.Equal(t, `# database
+APP__MONGO__HOST=localhost
+export APP__MONGO__PORT='27018'
+OTHER=1
+APP__MONGO__REPLICA_SET="rs0"
+`, readFile(t, path))
+
+	assert.Error(t, NewEnvSource(ENV, 1).Set("mongo.port", 1))
+}
+
+func TestSaveDetectsModifiedFile(t *testing.T) {
+	path := writeTemp(t, "config.yaml", "port: 80\n")
+	b := New().Source(path, 1).WriteTo(path)
+	b.Merged()
+
+	require.NoError(t, os.WriteFile(path, []byte("port: 81\n"), 0o640))
+	require.NoError(t, b.Set("port", 8080))
+
+	var modified *ModifiedError
+	require.ErrorAs(t, b.Save(), &modified)
+	assert.Equal(t, path, modified.Path)
+	assert.Equal(t, "port: 81\n", readFile(t, path))
+
+	// reading the file again lets the change through
+	b.Reload()
+	require.NoError(t, b.Save())
+	value, _ := b.Lookup("port")
+	assert.Equal(t, 8080, value)
+}
+
+func TestSaveKeepsOtherSourcesCached(t *testing.T) {
+	written := writeTemp(t, "config.yaml", "port: 80\n")
+	other := writeTemp(t, "other.json", `{"host": "a"}`)
+	b := New().Source(written, 1).Source(other, 2).WriteTo(written)
+	b.Merged()
+
+	// only a reload would see this change
+	require.NoError(t, os.WriteFile(other, []byte(`{"host": "b"}`), 0o640))
+	require.NoError(t, b.Set("port", 8080))
+	require.NoError(t, b.Save())
+
+	port, _ := b.Lookup("port")
+	assert.Equal(t, 8080, port)
+	host, _ := b.Lookup("host")
+	assert.Equal(t, "a", host)
+}
+
+func TestBuilderSetErrors(t *testing.T) {
+	b := New().AddSource(&stubSource{name: "stub", priority: 1})
+	assert.ErrorContains(t, b.Set("port", 1), "call WriteTo first")
+	assert.ErrorContains(t, b.WriteTo("missing").Set("port", 1), "source missing not found")
+	assert.ErrorContains(t, b.WriteTo("stub").Set("port", 1), "source stub is not writable")
+
+	var src WritableSource = NewJsonSource(filepath.Join(t.TempDir(), "new.json"), "", 1)
+	require.NoError(t, src.Set("port", 1))
+	require.NoError(t, src.Save())
+	data, err := src.Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"port": int64(1)}, data)
+	assert.False(t, errors.Is(src.Save(), os.ErrNotExist))
+}
+
+func TestTomlString(t *testing.T) {
+	assert.Equal(t, `"say \"hi\"\\\n\u0001"`, tomlString("say \"hi\"\\\n\x01"))
+}
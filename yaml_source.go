@@ -1,48 +1,513 @@
 package ascanius
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"reflect"
+	"slices"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
-const YAML_SOURCE_NAME = "yaml"
+const (
+	YAML_SOURCE_NAME = "yaml"
+	// YAML_PROFILE_KEY is the top-level key naming the profile a YAML
+	// document belongs to
+	YAML_PROFILE_KEY = "profile"
+)
 
 type YamlSource struct {
 	name     string
 	path     string
 	priority int
+	profile  string
+	state    *fileState
 }
 
-func NewYamlSource(path string, name string, priority int) *YamlSource {
+func NewYamlSource(path string, name string, priority int, opts ...func(*YamlSource)) *YamlSource {
 	if name == "" {
 		name = path
 	}
-	return &YamlSource{
+	t := &YamlSource{
 		name:     name,
 		path:     path,
 		priority: priority,
+		state:    &fileState{},
+	}
+	for _, opt := range opts {
+		opt(t)
 	}
+	return t
 }
 
-func (t *YamlSource) Load() (map[string]any, error) {
-	result := make(map[string]any)
+// WithYamlProfile selects the documents of a multi-document file whose
+// profile key names profile, in addition to those without a profile key.
+func WithYamlProfile(profile string) func(*YamlSource) {
+	return func(t *YamlSource) {
+		t.profile = profile
+	}
+}
 
+// Load merges the documents of the file in order. Documents with a profile
+// key are skipped unless it names the source's profile; the key itself is
+// dropped. Anchors and << merge keys are resolved, and keys that are not
+// strings, such as numbers, are converted to strings.
+func (t *YamlSource) Load() (map[string]any, error) {
 	bytes, err := os.ReadFile(t.path)
 	if err != nil {
 		return nil, err
 	}
 
-	err = yaml.Unmarshal(bytes, &result)
+	result, err := decodeYaml(bytes, t.profile)
+	if err != nil {
+		return nil, newParseError(t.path, bytes, err)
+	}
+	t.state.loaded(bytes)
+
+	return result, nil
+}
+
+// decodeYaml merges the documents of content as YamlSource.Load does,
+// selecting the documents of profile.
+func decodeYaml(content []byte, profile string) (map[string]any, error) {
+	docs, err := decodeYamlDocuments(content)
 	if err != nil {
 		return nil, err
 	}
 
+	result := make(map[string]any)
+	for i, doc := range docs {
+		value, err := newYamlDecoder().value(doc)
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %w", i+1, err)
+		}
+		m, ok := value.(map[string]any)
+		if value == nil {
+			continue
+		}
+		if !ok {
+			return nil, fmt.Errorf("document %d is not a mapping", i+1)
+		}
+		if name, ok := m[YAML_PROFILE_KEY]; ok {
+			if !matchesProfile(name, profile) {
+				continue
+			}
+			delete(m, YAML_PROFILE_KEY)
+		}
+		result = mergeMaps(result, m)
+	}
 	return result, nil
 }
 
+// matchesProfile reports whether the profile key of a document, a name or a
+// list of names, selects profile.
+func matchesProfile(value any, profile string) bool {
+	if profile == "" {
+		return false
+	}
+	switch v := value.(type) {
+	case string:
+		return v == profile
+	case []any:
+		for _, item := range v {
+			if item == profile {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func decodeYamlDocuments(content []byte) ([]*yaml.Node, error) {
+	var docs []*yaml.Node
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); errors.Is(err, io.EOF) {
+			return docs, nil
+		} else if err != nil {
+			return nil, err
+		}
+		docs = append(docs, &doc)
+	}
+}
+
+const (
+	yamlMergeTag = "!!merge"
+
+	// like yaml.v3, the share of nodes decoded through aliases is limited to
+	// 99% up to yamlAliasRatioLow nodes, scaling down to 10% at
+	// yamlAliasRatioHigh, so that a small file cannot expand to gigabytes
+	yamlAliasRatioLow  = 400000
+	yamlAliasRatioHigh = 4000000
+)
+
+// yamlDecoder converts the nodes of one document, guarding against
+// recursive anchors and excessive alias expansion.
+type yamlDecoder struct {
+	// expanding holds the anchors whose aliases are being expanded
+	expanding  map[*yaml.Node]bool
+	aliasDepth int
+	nodes      int
+	aliased    int
+}
+
+func newYamlDecoder() *yamlDecoder {
+	return &yamlDecoder{expanding: make(map[*yaml.Node]bool)}
+}
+
+func allowedYamlAliasRatio(nodes int) float64 {
+	switch {
+	case nodes <= yamlAliasRatioLow:
+		return 0.99
+	case nodes >= yamlAliasRatioHigh:
+		return 0.10
+	default:
+		return 0.99 - 0.89*float64(nodes-yamlAliasRatioLow)/float64(yamlAliasRatioHigh-yamlAliasRatioLow)
+	}
+}
+
+// value converts node to maps with string keys, slices and scalars,
+// resolving aliases and merge keys.
+func (d *yamlDecoder) value(node *yaml.Node) (any, error) {
+	d.nodes++
+	if d.aliasDepth > 0 {
+		d.aliased++
+	}
+	if d.aliased > 100 && d.nodes > 1000 && float64(d.aliased)/float64(d.nodes) > allowedYamlAliasRatio(d.nodes) {
+		return nil, errors.New("document contains excessive aliasing")
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return d.value(node.Content[0])
+
+	case yaml.AliasNode:
+		if d.expanding[node.Alias] {
+			return nil, fmt.Errorf("line %d: anchor %q value contains itself", node.Line, node.Value)
+		}
+		d.expanding[node.Alias] = true
+		d.aliasDepth++
+		defer func() {
+			delete(d.expanding, node.Alias)
+			d.aliasDepth--
+		}()
+		return d.value(node.Alias)
+
+	case yaml.SequenceNode:
+		out := make([]any, len(node.Content))
+		for i, item := range node.Content {
+			value, err := d.value(item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = value
+		}
+		return out, nil
+
+	case yaml.MappingNode:
+		out := make(map[string]any)
+		// explicit keys win over merged ones, and earlier merges over later ones
+		var merged []map[string]any
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Kind == yaml.ScalarNode && key.Tag == yamlMergeTag {
+				maps, err := d.mergeMaps(value)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", key.Line, err)
+				}
+				merged = append(merged, maps...)
+				continue
+			}
+
+			k, err := d.key(key)
+			if err != nil {
+				return nil, err
+			}
+			if out[k], err = d.value(value); err != nil {
+				return nil, err
+			}
+		}
+		for _, m := range merged {
+			for k, v := range m {
+				if _, ok := out[k]; !ok {
+					out[k] = deepCopyValue(v)
+				}
+			}
+		}
+		return out, nil
+
+	default:
+		var value any
+		if err := node.Decode(&value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+}
+
+// mergeMaps returns the mappings referenced by the value of a << key.
+func (d *yamlDecoder) mergeMaps(node *yaml.Node) ([]map[string]any, error) {
+	if node.Kind == yaml.SequenceNode {
+		var out []map[string]any
+		for _, item := range node.Content {
+			maps, err := d.mergeMaps(item)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, maps...)
+		}
+		return out, nil
+	}
+	value, err := d.value(node)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := value.(map[string]any)
+	if !ok {
+		return nil, errors.New("<< must merge a mapping or a list of mappings")
+	}
+	return []map[string]any{m}, nil
+}
+
+// key returns the string form of a mapping key, e.g. "8080" for an integer
+// key.
+func (d *yamlDecoder) key(node *yaml.Node) (string, error) {
+	if node.Kind == yaml.AliasNode && node.Alias.Kind == yaml.ScalarNode {
+		node = node.Alias
+	}
+	if node.Kind == yaml.ScalarNode {
+		return node.Value, nil
+	}
+	value, err := d.value(node)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprint(value), nil
+}
+
 func (t *YamlSource) Name() string        { return t.name }
 func (t *YamlSource) SetName(name string) { t.name = name }
 func (t *YamlSource) Priority() int       { return t.priority }
 func (t *YamlSource) SetPriority(p int)   { t.priority = p }
 func (t *YamlSource) Type() string        { return YAML_SOURCE_NAME }
+
+// Set records a new value for key, written by Save.
+func (t *YamlSource) Set(key string, value any) error {
+	return t.state.set(t.path, key, value)
+}
+
+// Save writes the values recorded by Set, keeping comments and key order.
+func (t *YamlSource) Save() error {
+	return t.state.save(t.path, editYaml)
+}
+
+func (t *YamlSource) hasPending() bool {
+	return t.state.hasPending()
+}
+
+// editYaml sets or removes e in a YAML document. A single-line scalar
+// replaced by a scalar is edited in place so the rest of the file is kept
+// byte for byte; other changes re-encode the document, which keeps comments
+// and key order but not blank lines.
+func editYaml(content []byte, e fileEdit) ([]byte, error) {
+	docs, err := decodeYamlDocuments(content)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) > 1 {
+		return nil, errors.New("files with several YAML documents cannot be edited")
+	}
+	var doc yaml.Node
+	if len(docs) == 1 {
+		doc = *docs[0]
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, errors.New("the document is not a YAML mapping")
+	}
+
+	parts := strings.Split(e.key, ".")
+	if e.remove {
+		if !removeYaml(root, parts) {
+			return content, nil
+		}
+		return encodeYaml(&doc, content)
+	}
+
+	var value yaml.Node
+	if err := value.Encode(e.value); err != nil {
+		return nil, err
+	}
+
+	node := root
+	for i, part := range parts {
+		var key, child *yaml.Node
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			if toSnakeCase(node.Content[j].Value) == part {
+				key, child = node.Content[j], node.Content[j+1]
+				break
+			}
+		}
+
+		if i == len(parts)-1 {
+			if child == nil {
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: part}, &value)
+				break
+			}
+			if edited, ok := spliceYamlScalar(content, child, &value, e); ok {
+				return edited, nil
+			}
+			value.HeadComment, value.FootComment = child.HeadComment, child.FootComment
+			if value.Kind == yaml.ScalarNode {
+				value.LineComment = child.LineComment
+			} else if key.LineComment == "" {
+				// keep the comment on the line of the key
+				key.LineComment = child.LineComment
+			}
+			*child = value
+			break
+		}
+
+		if child == nil {
+			child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: part}, child)
+		} else if child.Kind != yaml.MappingNode {
+			if key.LineComment == "" {
+				key.LineComment = child.LineComment
+			}
+			*child = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", HeadComment: child.HeadComment}
+		}
+		node = child
+	}
+	return encodeYaml(&doc, content)
+}
+
+// encodeYaml encodes doc with the indentation of content.
+func encodeYaml(doc *yaml.Node, content []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(len(detectIndent(content, "  ")))
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// removeYaml removes the key at parts from the mapping node and the mappings
+// it leaves empty, reporting whether the key was found.
+func removeYaml(node *yaml.Node, parts []string) bool {
+	for j := 0; j+1 < len(node.Content); j += 2 {
+		if toSnakeCase(node.Content[j].Value) != parts[0] {
+			continue
+		}
+		if child := node.Content[j+1]; len(parts) > 1 {
+			if child.Kind != yaml.MappingNode || !removeYaml(child, parts[1:]) {
+				return false
+			}
+			if len(child.Content) > 0 {
+				return true
+			}
+		}
+		node.Content = slices.Delete(node.Content, j, j+2)
+		return true
+	}
+	return false
+}
+
+// spliceYamlScalar replaces the text of the single-line scalar old with
+// value. ok is false when the change cannot be made in place.
+func spliceYamlScalar(content []byte, old, value *yaml.Node, e fileEdit) ([]byte, bool) {
+	if old.Kind != yaml.ScalarNode || value.Kind != yaml.ScalarNode || old.Anchor != "" ||
+		old.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		return nil, false
+	}
+	text, err := yaml.Marshal(value)
+	if err != nil || bytes.Count(text, []byte("\n")) > 1 {
+		return nil, false
+	}
+	text = bytes.TrimSuffix(text, []byte("\n"))
+
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	if old.Line < 1 || old.Line > len(lines) {
+		return nil, false
+	}
+	offset := 0
+	for _, line := range lines[:old.Line-1] {
+		offset += len(line)
+	}
+	line := lines[old.Line-1]
+	start := old.Column - 1
+	if start < 0 || start > len(line) {
+		return nil, false
+	}
+	end := start + yamlScalarLength(line[start:], old.Style)
+	if end <= start {
+		return nil, false
+	}
+
+	edited := append(append(append([]byte{}, content[:offset+start]...), text...), content[offset+end:]...)
+	// make sure the edit produced the document we expect
+	var check map[string]any
+	if err := yaml.Unmarshal(edited, &check); err != nil {
+		return nil, false
+	}
+	got, ok := lookupPath(normalizeKeysToSnakeCase(check), e.key)
+	if !ok || !reflect.DeepEqual(got, e.value) && !reflect.DeepEqual(got, yamlValue(value)) {
+		return nil, false
+	}
+	return edited, true
+}
+
+// yamlScalarLength returns the length of the scalar at the start of rest,
+// or 0 when it does not end on this line.
+func yamlScalarLength(rest []byte, style yaml.Style) int {
+	switch {
+	case style&yaml.DoubleQuotedStyle != 0:
+		for i := 1; i < len(rest); i++ {
+			switch rest[i] {
+			case '\\':
+				i++
+			case '"':
+				return i + 1
+			}
+		}
+		return 0
+	case style&yaml.SingleQuotedStyle != 0:
+		for i := 1; i < len(rest); i++ {
+			if rest[i] == '\'' {
+				if i+1 < len(rest) && rest[i+1] == '\'' {
+					i++
+					continue
+				}
+				return i + 1
+			}
+		}
+		return 0
+	default:
+		end := len(bytes.TrimRight(rest, "\r\n"))
+		if i := bytes.Index(rest[:end], []byte(" #")); i >= 0 {
+			end = i
+		}
+		return len(bytes.TrimRight(rest[:end], " \t"))
+	}
+}
+
+func yamlValue(node *yaml.Node) any {
+	var v any
+	node.Decode(&v)
+	return v
+}
@@ -0,0 +1,154 @@
+package ascanius
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const profilesYaml = `name: api
+mongo:
+  host: localhost
+  port: 27017
+---
+profile: prod
+mongo:
+  host: mongo.internal
+---
+profile: [staging, qa]
+mongo:
+  port: 27018
+---
+log: debug
+`
+
+func TestYamlMultiDocument(t *testing.T) {
+	path := writeTemp(t, "config.yaml", profilesYaml)
+
+	data, err := NewYamlSource(path, "", 1).Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"name":  "api",
+		"mongo": map[string]any{"host": "localhost", "port": 27017},
+		"log":   "debug",
+	}, data)
+
+	data, err = NewYamlSource(path, "", 1, WithYamlProfile("prod")).Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"name":  "api",
+		"mongo": map[string]any{"host": "mongo.internal", "port": 27017},
+		"log":   "debug",
+	}, data)
+
+	data, err = NewYamlSource(path, "", 1, WithYamlProfile("qa")).Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"host": "localhost", "port": 27018}, data["mongo"])
+}
+
+func TestBuilderProfile(t *testing.T) {
+	path := writeTemp(t, "config.yaml", profilesYaml)
+
+	var cfg struct {
+		Mongo struct {
+			Host string
+			Port int
+		}
+	}
+	require.NoError(t, New().Profile("prod").Source(path, 1).Load(&cfg).Err())
+	assert.Equal(t, "mongo.internal", cfg.Mongo.Host)
+	assert.Equal(t, 27017, cfg.Mongo.Port)
+}
+
+func TestYamlMergeKeys(t *testing.T) {
+	path := writeTemp(t, "config.yaml", `defaults: &defaults
+  timeout: 5s
+  retries: 3
+tls: &tls
+  tls: true
+  retries: 10
+primary:
+  <<: *defaults
+  host: a
+replica:
+  <<: [*defaults, *tls]
+  retries: 1
+  host: b
+tags: &tags [x, y]
+copy: *tags
+`)
+	data, err := NewYamlSource(path, "", 1).Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"timeout": "5s", "retries": 3, "host": "a"}, data["primary"])
+	assert.Equal(t, map[string]any{"timeout": "5s", "retries": 1, "tls": true, "host": "b"}, data["replica"])
+	assert.Equal(t, []any{"x", "y"}, data["copy"])
+
+	// merged values are copies
+	data["primary"].(map[string]any)["timeout"] = "1s"
+	assert.Equal(t, "5s", data["defaults"].(map[string]any)["timeout"])
+}
+
+func TestYamlNonStringKeys(t *testing.T) {
+	path := writeTemp(t, "config.yaml", `ports:
+  8080: http
+  443: https
+flags:
+  true: on
+  1.5: half
+`)
+	data, err := NewYamlSource(path, "", 1).Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"8080": "http", "443": "https"}, data["ports"])
+	assert.Equal(t, map[string]any{"true": "on", "1.5": "half"}, data["flags"])
+
+	var cfg struct {
+		Ports map[string]string
+	}
+	require.NoError(t, New().Source(path, 1).Load(&cfg).Err())
+	assert.Equal(t, "https", cfg.Ports["443"])
+}
+
+func TestYamlInvalidDocuments(t *testing.T) {
+	var perr *ParseError
+
+	_, err := NewYamlSource(writeTemp(t, "list.yaml", "a: 1\n---\n- b\n"), "", 1).Load()
+	require.ErrorAs(t, err, &perr)
+	assert.Contains(t, err.Error(), "document 2 is not a mapping")
+
+	_, err = NewYamlSource(writeTemp(t, "merge.yaml", "a:\n  <<: 1\n"), "", 1).Load()
+	require.ErrorAs(t, err, &perr)
+
+	// several documents cannot be edited or migrated in place
+	path := writeTemp(t, "config.yaml", profilesYaml)
+	src := NewYamlSource(path, "", 1)
+	require.NoError(t, src.Set("name", "web"))
+	assert.Error(t, src.Save())
+	assert.Equal(t, profilesYaml, readFile(t, path))
+
+	_, _, err = MigrateFile(path, SetKey("name", "web"))
+	assert.Error(t, err)
+}
+
+func TestYamlAliasLimits(t *testing.T) {
+	var perr *ParseError
+
+	_, err := NewYamlSource(writeTemp(t, "cycle.yaml", "a: &a\n  b: *a\n"), "", 1).Load()
+	require.ErrorAs(t, err, &perr)
+	assert.Contains(t, err.Error(), `anchor "a" value contains itself`)
+
+	laughs := `a: &a ["lol","lol","lol","lol","lol","lol","lol","lol","lol"]
+b: &b [*a,*a,*a,*a,*a,*a,*a,*a,*a]
+c: &c [*b,*b,*b,*b,*b,*b,*b,*b,*b]
+d: &d [*c,*c,*c,*c,*c,*c,*c,*c,*c]
+e: &e [*d,*d,*d,*d,*d,*d,*d,*d,*d]
+f: &f [*e,*e,*e,*e,*e,*e,*e,*e,*e]
+g: &g [*f,*f,*f,*f,*f,*f,*f,*f,*f]
+`
+	start := time.Now()
+	_, err = NewYamlSource(writeTemp(t, "laughs.yaml", laughs), "", 1).Load()
+	require.ErrorAs(t, err, &perr)
+	assert.Contains(t, err.Error(), "excessive aliasing")
+	assert.Less(t, time.Since(start), time.Second)
+}